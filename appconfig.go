@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appConfigFile is alacritheme's own config, kept in appConfigDir
+const appConfigFile = "config.toml"
+
+// appConfig is alacritheme's own configuration
+type appConfig struct {
+	ThemesDir  string            `toml:"themes_dir,omitempty"`
+	ThemesDirs []string          `toml:"themes_dirs,omitempty"`
+	ConfigFile string            `toml:"config_file,omitempty"`
+	Offline    bool              `toml:"offline,omitempty"`
+	Locks      map[string]string `toml:"locks,omitempty"`
+	Keys       map[string]string `toml:"keys,omitempty"`
+	Sort       string            `toml:"sort,omitempty"`
+	// MinStars hides the registry themes an index gives fewer stars
+	MinStars int           `toml:"registry_min_stars,omitempty"`
+	Toggle   toggleConfig  `toml:"toggle,omitempty"`
+	Preview  previewConfig `toml:"preview,omitempty"`
+	Network  netConfig     `toml:"network,omitempty"`
+	Sync     syncConfig    `toml:"sync,omitempty"`
+	Export   exportConfig  `toml:"export,omitempty"`
+	// Registries are remote theme sources listed in the TUI, by name: git
+	// repositories or HTTP index.json files
+	Registries map[string]string `toml:"registries,omitempty"`
+	// HideBuiltin leaves the themes compiled into alacritheme out of the
+	// list
+	HideBuiltin bool `toml:"hide_builtin_themes,omitempty"`
+	// Backends are the terminals the TUI applies themes to next to
+	// Alacritty, detected when unset
+	Backends []string `toml:"backends,omitempty"`
+	// Apply is how themes are written into Alacritty's config
+	Apply applyConfig `toml:"apply,omitempty"`
+	// Backups is how many backups of the Alacritty config are kept
+	Backups backupsConfig `toml:"backups,omitempty"`
+}
+
+// backupsConfig limits the backups of the Alacritty config kept, to
+// defaultBackupsKept when unset
+type backupsConfig struct {
+	Keep int `toml:"keep,omitempty"`
+}
+
+// toggleConfig is the light/dark pair switched between by the toggle command
+type toggleConfig struct {
+	Dark  string `toml:"dark,omitempty"`
+	Light string `toml:"light,omitempty"`
+}
+
+// Sort orders of the theme list
+const (
+	sortByName       = "name"
+	sortByModified   = "modified"
+	sortByPopularity = "popularity"
+)
+
+// previewConfig changes what the theme preview shows
+type previewConfig struct {
+	// Details is what's shown below each color: "all" (hex value and
+	// closest color name, the default), "hex" or "none"
+	Details string `toml:"details,omitempty"`
+	Columns int    `toml:"columns,omitempty"`
+	// Compare starts the TUI with the preview in compare mode
+	Compare bool `toml:"compare,omitempty"`
+	// Transition fades the colors previewed over IPC from one theme to the
+	// next instead of switching them at once
+	Transition bool `toml:"transition,omitempty"`
+}
+
+// columns returns how many colors the preview shows per row
+func (p previewConfig) columns() int {
+	if p.Columns <= 0 {
+		return 4
+	}
+
+	return p.Columns
+}
+
+// validate reports settings the TUI can't use
+func (cfg appConfig) validate() error {
+	switch cfg.Sort {
+	case "", sortByName, sortByModified, sortByPopularity:
+	default:
+		return fmt.Errorf("sort: unknown order %q, expected %q, %q or %q", cfg.Sort, sortByName, sortByModified, sortByPopularity)
+	}
+
+	if cfg.MinStars < 0 {
+		return fmt.Errorf("registry_min_stars: %d is out of range, expected 0 or more", cfg.MinStars)
+	}
+
+	switch cfg.Preview.Details {
+	case "", "all", "hex", "none":
+	default:
+		return fmt.Errorf("preview.details: unknown value %q, expected \"all\", \"hex\" or \"none\"", cfg.Preview.Details)
+	}
+
+	if cfg.Preview.Columns < 0 || cfg.Preview.Columns > 8 {
+		return fmt.Errorf("preview.columns: %d is out of range, expected 1 to 8", cfg.Preview.Columns)
+	}
+
+	for _, name := range cfg.Backends {
+		if _, ok := otherBackends[name]; !ok && name != "alacritty" {
+			return fmt.Errorf("backends: unknown terminal %q, expected one of alacritty, %s", name, strings.Join(otherBackendNames(), ", "))
+		}
+	}
+
+	switch cfg.Apply.Mode {
+	case "", applyByImport, applyInline, applyInclude:
+	default:
+		return fmt.Errorf("apply.mode: unknown mode %q, expected %q, %q or %q", cfg.Apply.Mode, applyByImport, applyInline, applyInclude)
+	}
+
+	if cfg.Backups.Keep < 0 {
+		return fmt.Errorf("backups.keep: %d is out of range, expected 1 or more", cfg.Backups.Keep)
+	}
+
+	if _, err := lookupFormat(cfg.Export.format()); err != nil {
+		return fmt.Errorf("export.format: %w", err)
+	}
+
+	return validateRegistries(cfg.Registries)
+}
+
+// Apply modes, how a theme gets into Alacritty's config
+const (
+	applyByImport = "import"
+	applyInline   = "inline"
+	applyInclude  = "include"
+)
+
+// applyConfig changes how themes are written into Alacritty's config
+type applyConfig struct {
+	// Mode is applyByImport, the default, applyInline to copy the theme's
+	// colors into the config, for machines without the themes, or
+	// applyInclude to import the theme in a file of alacritheme's own
+	Mode string `toml:"mode,omitempty"`
+	// KeepLiveReload leaves live_config_reload off for previews too, when
+	// the config turns it off
+	KeepLiveReload bool `toml:"keep_live_reload,omitempty"`
+}
+
+// applyOptions returns how themes are written into Alacritty's config
+func (cfg appConfig) applyOptions() applyOptions {
+	mode := cfg.Apply.Mode
+	if mode == "" {
+		mode = applyByImport
+	}
+
+	return applyOptions{locks: cfg.Locks, mode: mode}
+}
+
+// previewOptions returns how the TUI writes previews into Alacritty's config
+func (cfg appConfig) previewOptions() applyOptions {
+	opts := cfg.applyOptions()
+	opts.liveReload = !cfg.Apply.KeepLiveReload
+	return opts
+}
+
+// netConfig overrides how network features connect, for networks that
+// can't reach GitHub directly
+type netConfig struct {
+	Proxy    string `toml:"proxy,omitempty"`
+	CABundle string `toml:"ca_bundle,omitempty"`
+}
+
+// exportConfig is where the TUI's export key writes the selected theme
+type exportConfig struct {
+	// Format is one of exportFormats, "wezterm" by default
+	Format string `toml:"format,omitempty"`
+	Dir    string `toml:"dir,omitempty"`
+}
+
+// format returns the name of the export format
+func (e exportConfig) format() string {
+	if e.Format == "" {
+		return "wezterm"
+	}
+
+	return e.Format
+}
+
+// syncConfig points at the team's shared theme catalog, and Tmux keeps
+// tmux's colors in step with every applied theme
+type syncConfig struct {
+	Remote string `toml:"remote,omitempty"`
+	Tmux   bool   `toml:"tmux,omitempty"`
+}
+
+// loadAppConfig reads alacritheme's config and reports whether it exists
+func loadAppConfig() (appConfig, bool, error) {
+	var cfg appConfig
+
+	dir, err := appConfigDir()
+	if err != nil {
+		return cfg, false, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, appConfigFile)); os.IsNotExist(err) {
+		return cfg, false, nil
+	}
+
+	if err := loadState(appConfigFile, &cfg); err != nil {
+		return cfg, true, err
+	}
+
+	cfg.ThemesDir = expandHome(cfg.ThemesDir)
+	for i, dir := range cfg.ThemesDirs {
+		cfg.ThemesDirs[i] = expandHome(dir)
+	}
+	cfg.ConfigFile = expandHome(cfg.ConfigFile)
+	cfg.Network.CABundle = expandHome(cfg.Network.CABundle)
+	cfg.Export.Dir = expandHome(cfg.Export.Dir)
+	return cfg, true, nil
+}
+
+// saveAppConfig writes alacritheme's config
+func saveAppConfig(cfg appConfig) error {
+	return saveState(appConfigFile, cfg)
+}
+
+// themesDirFlag and configFlag are the paths given with --themes-dir and
+// --config, which take precedence over the environment and the config
+var (
+	themesDirFlag string
+	configFlag    string
+)
+
+// checkPaths checks that the paths given with --themes-dir and --config
+// exist
+func checkPaths() error {
+	for _, dir := range filepath.SplitList(themesDirFlag) {
+		info, err := os.Stat(expandHome(dir))
+		if os.IsNotExist(err) {
+			return fmt.Errorf("--themes-dir: %s does not exist", dir)
+		} else if err != nil {
+			return fmt.Errorf("--themes-dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("--themes-dir: %s is not a directory", dir)
+		}
+	}
+
+	if configFlag != "" {
+		// A missing config is created on start, as long as its directory exists
+		info, err := os.Stat(configFlag)
+		if os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Dir(configFlag)); err != nil {
+				return fmt.Errorf("--config: neither %s nor its directory exist", configFlag)
+			}
+		} else if err != nil {
+			return fmt.Errorf("--config: %w", err)
+		} else if info.IsDir() {
+			return fmt.Errorf("--config: %s is a directory, pass Alacritty's config file, e.g. %s", configFlag, filepath.Join(configFlag, "alacritty.toml"))
+		}
+	}
+
+	return nil
+}
+
+// resolvePaths returns the primary themes directory and Alacritty config to
+// use, taken from --themes-dir and --config, then THEMES_DIR and CONFIG_FILE,
+// with alacritheme's config and the config Alacritty itself reads as fallback
+func resolvePaths() (themesDir, configFile string, err error) {
+	dirs, err := resolveThemeDirs()
+	if len(dirs) > 0 {
+		themesDir = dirs[0]
+	}
+
+	configFile = configFlag
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+	if configFile == "" {
+		cfg, _, _ := loadAppConfig()
+		configFile = cfg.ConfigFile
+	}
+	if configFile == "" {
+		configFile = discoverConfig()
+	}
+
+	return themesDir, configFile, err
+}
+
+// resolveThemeDirs returns every themes directory to list. --themes-dir,
+// THEMES_DIR and themes_dir may hold several, separated like PATH, and themes_dirs adds
+// more. The first one is the primary directory, where downloaded and
+// synced themes go.
+func resolveThemeDirs() ([]string, error) {
+	cfg, _, err := loadAppConfig()
+
+	var dirs []string
+	if themesDirFlag != "" {
+		dirs = filepath.SplitList(themesDirFlag)
+	} else if env := os.Getenv("THEMES_DIR"); env != "" {
+		dirs = filepath.SplitList(env)
+	} else {
+		dirs = append(filepath.SplitList(cfg.ThemesDir), cfg.ThemesDirs...)
+	}
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, dir := range dirs {
+		dir = filepath.Clean(expandHome(dir))
+		if dir == "." || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		unique = append(unique, dir)
+	}
+
+	return unique, err
+}
+
+// sourceLabels names each themes directory for display by its base name,
+// adding parent directories until the names are unique
+func sourceLabels(dirs []string) []string {
+	labels := make([]string, len(dirs))
+	for depth := 1; ; depth++ {
+		count := make(map[string]int)
+		for i, dir := range dirs {
+			labels[i] = lastElems(dir, depth)
+			count[labels[i]]++
+		}
+
+		unique := true
+		for _, n := range count {
+			unique = unique && n == 1
+		}
+		if unique || depth > 8 {
+			return labels
+		}
+	}
+}
+
+// lastElems returns the last n elements of path
+func lastElems(path string, n int) string {
+	elems := strings.Split(filepath.ToSlash(path), "/")
+	if n < len(elems) {
+		elems = elems[len(elems)-n:]
+	}
+
+	return filepath.FromSlash(strings.Join(elems, "/"))
+}
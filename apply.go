@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// runApply points the Alacritty config at a theme, or prints the change as a
+// diff with --dry-run: apply <theme>
+func runApply(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("apply", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usage("apply")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	dirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	theme, err := resolveTheme(dirs, args[0])
+	if err != nil {
+		return err
+	}
+	trace("resolved theme %s to %s", args[0], theme)
+
+	return applyThemeFile(configFile, theme)
+}
+
+// applyThemeFile points configFile at the theme file, or prints the change
+// as a diff with --dry-run
+func applyThemeFile(configFile, theme string) error {
+	_, content, err := appliedTheme(configFile)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		cfg, _, err := loadAppConfig()
+		if err != nil {
+			return err
+		}
+		var config map[string]interface{}
+		if err := toml.Unmarshal(content, &config); err != nil {
+			return err
+		}
+		files, err := themeFiles(content, config, configFile, theme, cfg.applyOptions())
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, file := range files {
+			current := content
+			if file.path != configFile {
+				current, _ = os.ReadFile(file.path)
+			}
+			if diff := unifiedDiff(file.path, file.path, string(current), string(file.content)); diff != "" {
+				result("%s", diff)
+				changed = true
+			}
+		}
+		if !changed {
+			info("Nothing would change, %s is already applied.\n", filepath.Base(theme))
+		}
+		return nil
+	}
+
+	if err := setTheme(configFile, content, theme); err != nil {
+		return err
+	}
+
+	result("%s\n", filepath.Base(theme))
+	return nil
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// backend is a terminal the TUI applies themes to. The selected theme is
+// previewed as the cursor rests on it, applied when it's kept with enter,
+// and the terminal's own colors are restored when quitting without one.
+type backend interface {
+	// Name identifies the terminal in messages
+	Name() string
+	// Detect reports whether the terminal is there to take themes
+	Detect() bool
+	// Preview shows the theme at path while it's selected
+	Preview(path string) error
+	// Apply keeps the theme at path
+	Apply(path string) error
+	// Restore brings back the colors the terminal had before the first
+	// preview
+	Restore() error
+}
+
+// alacrittyBackend applies themes by rewriting the import of Alacritty's
+// config, its colors when inlining, or the include file, which Alacritty
+// reloads. Inside an
+// Alacritty with IPC, previews are sent over its socket instead, and the
+// config is only written when a theme is applied. Nothing is changed with
+// --dry-run.
+type alacrittyBackend struct {
+	configFile string
+	// opts are how applied themes are written, and preview how previews are
+	opts    applyOptions
+	preview applyOptions
+	// original is the config's content before the first preview and config
+	// its parsed form, which every preview starts from
+	original []byte
+	config   map[string]interface{}
+	// backupFile is the copy of the original config saved by backup, none
+	// with --dry-run, and theme the theme it imports
+	backupFile string
+	theme      string
+	// include is the include file before the first preview, with nil
+	// content if it didn't exist
+	include generatedFile
+	// created tells whether backup created the config, which Restore
+	// removes again
+	created bool
+	// backupsKept is how many backups of the config backup keeps
+	backupsKept int
+	// transition fades between the colors sent over IPC
+	transition bool
+
+	// Previews are written in the background, written is the theme the
+	// config imports since the last one, and messaged tells whether the
+	// running Alacritty was sent colors
+	mu       sync.Mutex
+	written  string
+	messaged bool
+	// sent are the colors last sent over IPC, by their dotted keys
+	sent map[string]string
+	// reloading tells whether the written preview turned live reload on
+	reloading bool
+}
+
+func newAlacrittyBackend(configFile string, cfg appConfig) *alacrittyBackend {
+	return &alacrittyBackend{
+		configFile:  configFile,
+		opts:        cfg.applyOptions(),
+		preview:     cfg.previewOptions(),
+		config:      make(map[string]interface{}),
+		backupsKept: cfg.Backups.Keep,
+		transition:  cfg.Preview.Transition,
+	}
+}
+
+func (b *alacrittyBackend) Name() string { return "Alacritty" }
+
+// Detect reports whether Alacritty is installed or has a config
+func (b *alacrittyBackend) Detect() bool {
+	if fileExists(b.configFile) {
+		return true
+	}
+	_, err := exec.LookPath("alacritty")
+	return err == nil
+}
+
+// backup reads the config, creating an empty one where Alacritty looks for
+// it if there's none, and saves a copy of it before anything is previewed
+func (b *alacrittyBackend) backup() error {
+	if err := checkLegacyConfig(b.configFile); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(b.configFile)
+	if os.IsNotExist(err) && dryRun {
+		// Diff against an empty config without creating it
+		err = nil
+	} else if os.IsNotExist(err) {
+		// Start with an empty config where Alacritty will look for it
+		if err := os.MkdirAll(filepath.Dir(b.configFile), 0755); err != nil {
+			return err
+		}
+		err = os.WriteFile(b.configFile, nil, 0644)
+		b.created = err == nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	if err := toml.Unmarshal(content, &config); err != nil {
+		return err
+	}
+
+	b.original = content
+	b.config = config
+	b.include = generatedFile{path: includeFile(b.configFile)}
+	if b.include.content, err = os.ReadFile(b.include.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !dryRun {
+		if b.backupFile, err = saveBackup(b.configFile, content, b.backupsKept); err != nil {
+			return err
+		}
+	}
+	b.theme = importedTheme(config, b.configFile)
+	if b.theme != "" && !filepath.IsAbs(b.theme) {
+		// Alacritty resolves relative imports against the config's directory
+		b.theme = filepath.Join(filepath.Dir(b.configFile), b.theme)
+	} else if b.theme == "" {
+		b.theme = inlinedTheme(b.configFile)
+	}
+	return nil
+}
+
+// updated returns the original config with the theme at path applied
+func (b *alacrittyBackend) updated(path string) ([]byte, error) {
+	return configWithTheme(b.original, b.config, b.configFile, path, b.opts)
+}
+
+func (b *alacrittyBackend) Preview(path string) error {
+	if alacritty, ok := alacrittyIPC(); ok && !dryRun {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if err := b.message(alacritty, path); err != nil {
+			return err
+		}
+		b.messaged = true
+		return nil
+	}
+
+	return b.write(path, b.preview)
+}
+
+// write points the config at the theme at path, the way opts tell
+func (b *alacrittyBackend) write(path string, opts applyOptions) error {
+	files, err := themeFiles(b.original, b.config, b.configFile, path, opts)
+	if err != nil || dryRun {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := writeGenerated(files); err != nil {
+		return err
+	}
+	b.written = path
+	_, off := reloadOff(b.config)
+	b.reloading = off && opts.liveReload
+	if b.opts.mode == applyInline {
+		return saveInlined(b.configFile, path)
+	}
+	return nil
+}
+
+// message sends the colors of the theme at path, with the locked ones, to
+// every window of the running Alacritty, replacing the ones sent before
+func (b *alacrittyBackend) message(alacritty, path string) error {
+	content, err := readLocked(path, b.opts.locks)
+	if err != nil {
+		return err
+	}
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	values := make(map[string]string)
+	flattenConfig(theme, "", values)
+	if b.transition {
+		if err := b.fade(alacritty, values); err != nil {
+			return err
+		}
+	}
+
+	options := make([]string, 0, len(values))
+	for key, value := range values {
+		options = append(options, key+"="+value)
+	}
+	sort.Strings(options)
+
+	// --reset can't be combined with options, it drops the colors of the
+	// theme before, which the new one may not all set
+	if err := runAlacrittyMsg(alacritty, []string{"msg", "config", "--window-id", "-1", "--reset"}); err != nil {
+		return err
+	}
+	if err := runAlacrittyMsg(alacritty, append([]string{"msg", "config", "--window-id", "-1"}, options...)); err != nil {
+		return err
+	}
+	b.sent = values
+	return nil
+}
+
+// The colors sent on the way from one theme to the next, in transitionSteps
+// over transitionTime
+const (
+	transitionSteps = 6
+	transitionTime  = 300 * time.Millisecond
+)
+
+// fade sends the colors on the way from the ones Alacritty shows to values,
+// stopping a step short of them. Only the colors both set are faded, the
+// first preview fades from the theme the config had.
+func (b *alacrittyBackend) fade(alacritty string, values map[string]string) error {
+	from := b.sent
+	if from == nil {
+		from = make(map[string]string)
+		content, err := readLocked(b.theme, b.opts.locks)
+		if err != nil {
+			trace("not fading from %s: %v", b.theme, err)
+			return nil
+		}
+		var theme map[string]interface{}
+		if err := toml.Unmarshal(content, &theme); err != nil {
+			trace("not fading from %s: %v", b.theme, err)
+			return nil
+		}
+		flattenConfig(theme, "", from)
+	}
+
+	type fading struct {
+		key      string
+		from, to colorful.Color
+	}
+	var colors []fading
+	for key, value := range values {
+		to, err := parseColor(strings.Trim(value, `'"`))
+		if err != nil {
+			continue
+		}
+		was, err := parseColor(strings.Trim(from[key], `'"`))
+		if err != nil || was == to {
+			continue
+		}
+		colors = append(colors, fading{key, was, to})
+	}
+	if len(colors) == 0 {
+		return nil
+	}
+	sort.Slice(colors, func(i, j int) bool { return colors[i].key < colors[j].key })
+
+	for step := 1; step < transitionSteps; step++ {
+		t := float64(step) / transitionSteps
+		args := []string{"msg", "config", "--window-id", "-1"}
+		for _, color := range colors {
+			args = append(args, fmt.Sprintf("%s=%q", color.key, color.from.BlendLab(color.to, t).Clamped().Hex()))
+		}
+		if err := runAlacrittyMsg(alacritty, args); err != nil {
+			return err
+		}
+		time.Sleep(transitionTime / transitionSteps)
+	}
+	return nil
+}
+
+// Apply writes the config unless a preview already did, turning live reload
+// back off if the preview turned it on, and drops the colors sent to
+// Alacritty, which now come from the config
+func (b *alacrittyBackend) Apply(path string) error {
+	b.mu.Lock()
+	written, messaged, reloading := b.written, b.messaged, b.reloading
+	b.mu.Unlock()
+
+	if written != path || reloading {
+		if err := b.write(path, b.opts); err != nil {
+			return err
+		}
+	}
+	return b.reset(messaged)
+}
+
+func (b *alacrittyBackend) Restore() error {
+	if dryRun {
+		return nil
+	}
+
+	b.mu.Lock()
+	written, messaged := b.written, b.messaged
+	b.written, b.reloading, b.sent = "", false, nil
+	b.mu.Unlock()
+	if b.created {
+		// Alacritty goes back to running without a config
+		trace("removing %s", b.configFile)
+		if err := os.Remove(b.configFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// The config is untouched when previews only went over IPC
+	if written != "" {
+		if !b.created {
+			if err := writeConfig(b.configFile, b.original); err != nil {
+				return err
+			}
+		}
+		if err := b.restoreInclude(); err != nil {
+			return err
+		}
+		if b.opts.mode == applyInline {
+			if err := saveInlined(b.configFile, b.theme); err != nil {
+				return err
+			}
+		}
+	}
+	return b.reset(messaged)
+}
+
+// restoreInclude brings back the include file, removing it if it didn't
+// exist, once the config is restored not to import it
+func (b *alacrittyBackend) restoreInclude() error {
+	if b.opts.mode != applyInclude {
+		return nil
+	}
+	if b.include.content != nil {
+		return writeConfig(b.include.path, b.include.content)
+	}
+
+	trace("removing %s", b.include.path)
+	if err := os.Remove(b.include.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// reset drops the colors sent to the running Alacritty, if any were
+func (b *alacrittyBackend) reset(messaged bool) error {
+	alacritty, ok := alacrittyIPC()
+	if !messaged || !ok {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.messaged = false
+	b.mu.Unlock()
+	return runAlacrittyMsg(alacritty, []string{"msg", "config", "--window-id", "-1", "--reset"})
+}
+
+// alacrittyIPC returns the alacritty binary when alacritheme runs inside an
+// Alacritty listening on an IPC socket, which it tells in ALACRITTY_SOCKET
+func alacrittyIPC() (string, bool) {
+	if os.Getenv("ALACRITTY_SOCKET") == "" {
+		return "", false
+	}
+	alacritty, err := exec.LookPath("alacritty")
+	return alacritty, err == nil
+}
+
+// runAlacrittyMsg runs alacritty msg with args
+func runAlacrittyMsg(alacritty string, args []string) error {
+	trace("running alacritty %s", strings.Join(args, " "))
+	if out, err := exec.Command(alacritty, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("alacritty msg: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// otherBackends are the terminals themes can be applied to next to
+// Alacritty, by the name the backends setting lists them by
+var otherBackends = map[string]func() backend{
+	"ghostty": func() backend { return &ghosttyBackend{} },
+	"kitty":   func() backend { return &kittyBackend{} },
+	"tmux":    func() backend { return &tmuxBackend{} },
+	"wezterm": func() backend { return &weztermBackend{} },
+}
+
+// otherBackendNames returns the names of otherBackends in a stable order,
+// for error messages
+func otherBackendNames() []string {
+	names := make([]string, 0, len(otherBackends))
+	for name := range otherBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// configuredBackends returns the terminals the TUI applies themes to next to
+// Alacritty: the ones the backends setting lists, or else a kitty or Ghostty
+// alacritheme runs in (or a kitty whose socket it's given), WezTerm once its
+// config requires alacritheme's module, and tmux with sync.tmux. Terminals
+// that aren't there are left out.
+func configuredBackends(cfg appConfig) []backend {
+	names := cfg.Backends
+	if names == nil {
+		names = []string{"kitty", "ghostty", "wezterm"}
+		if cfg.Sync.Tmux {
+			names = append(names, "tmux")
+		}
+	}
+
+	var backends []backend
+	for _, name := range names {
+		create, ok := otherBackends[name]
+		if !ok {
+			// Alacritty is always applied to, and unknown names are
+			// reported by validate
+			continue
+		}
+		b := create()
+		if !b.Detect() {
+			trace("not applying themes to %s, it isn't running or set up", b.Name())
+			continue
+		}
+		backends = append(backends, b)
+	}
+
+	return backends
+}
+
+// eachBackend runs fn for every backend and names the ones that failed in a
+// single line, for the status bar
+func eachBackend(backends []backend, fn func(backend) error) error {
+	var failed []string
+	for _, b := range backends {
+		if err := fn(b); err != nil {
+			failed = append(failed, b.Name()+": "+err.Error())
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(failed, "; "))
+}
+
+// previewAll previews the theme at path through every backend
+func previewAll(backends []backend, path string) error {
+	return eachBackend(backends, func(b backend) error { return b.Preview(path) })
+}
+
+// applyAll applies the theme at path through every backend
+func applyAll(backends []backend, path string) error {
+	return eachBackend(backends, func(b backend) error { return b.Apply(path) })
+}
+
+// restoreAll restores the colors of every backend
+func restoreAll(backends []backend) error {
+	return eachBackend(backends, func(b backend) error { return b.Restore() })
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// builtinThemes are popular themes compiled into the binary, so there's
+// something to pick from before any theme is downloaded
+//
+//go:embed builtin/*.toml
+var builtinThemes embed.FS
+
+// builtinSource labels the built-in themes in the list
+const builtinSource = "built-in"
+
+var (
+	builtinOnce sync.Once
+	builtinPath string
+)
+
+// builtinRoot returns the directory the built-in themes are written to, as
+// Alacritty can only import files, or an empty string when they're hidden
+// with hide_builtin_themes or couldn't be written
+func builtinRoot() string {
+	builtinOnce.Do(func() {
+		if cfg, _, err := loadAppConfig(); err == nil && cfg.HideBuiltin {
+			return
+		}
+
+		dir, err := extractBuiltins()
+		if err != nil {
+			trace("couldn't write the built-in themes: %v", err)
+			return
+		}
+		builtinPath = dir
+	})
+
+	return builtinPath
+}
+
+// extractBuiltins writes the built-in themes into the state directory,
+// replacing the ones written by an older version, and returns it
+func extractBuiltins() (string, error) {
+	state, err := appStateDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(state, "builtin")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	entries, err := fs.ReadDir(builtinThemes, "builtin")
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		content, err := builtinThemes.ReadFile(path.Join("builtin", entry.Name()))
+		if err != nil {
+			return "", err
+		}
+
+		target := filepath.Join(dir, entry.Name())
+		if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		trace("writing %s", target)
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// isBuiltin reports whether the theme at path is a built-in one
+func isBuiltin(path string) bool {
+	root := builtinRoot()
+	if root == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
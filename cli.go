@@ -0,0 +1,296 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Exit statuses: a command that fails exits with exitFailure, one that is
+// called with the wrong arguments with exitUsage
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is a subcommand. run gets the arguments after its name, usage is
+// its synopsis and summary the line that describes it in help.
+type command struct {
+	run     func(args []string) error
+	usage   string
+	summary string
+}
+
+// defaultCommand runs when no command is given
+const defaultCommand = "tui"
+
+// commands are the subcommands. They're set in init, because help and the
+// completion scripts list them, which would be an initialization cycle.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"apply":       {runApply, "apply <theme>", "Point Alacritty's config at a theme"},
+		"completion":  {runCompletion, "completion bash|zsh|fish", "Print the shell completion script"},
+		"doctor":      {runDoctor, "doctor", "Diagnose the setup and suggest fixes"},
+		"explain":     {runExplain, "explain apply <theme>", "Show what applying a theme would change and why"},
+		"export":      {runExport, "export <theme> --to <format> [--out file] [--set-colors]", "Convert a theme for another program"},
+		"export-all":  {runExportAll, "export-all --to <format> --out <dir> [--jobs n]", "Convert every theme for another program"},
+		"fetch":       {runFetch, "fetch [--git] [--dir dir] [--force]", "Download or update the official alacritty-theme collection"},
+		"help":        {runHelp, "help [command]", "Show help for alacritheme or a command"},
+		"import":      {runImport, "import <file|dir|url> [--from format] [--name name] [--force]", "Convert another terminal's color scheme into a theme"},
+		"install":     {runInstall, "install <url> [--name name] [--apply] [--force]", "Download a theme from a URL into the themes directory"},
+		"list":        {runList, "list [--paths]", "List every theme"},
+		"lock":        {runLock, "lock [<color> <value>]", "Keep a color whichever theme is applied, or list the locked ones"},
+		"migrate":     {runMigrate, "migrate", "Convert a YAML config of Alacritty before 0.13 to TOML"},
+		"preview":     {runPreview, "preview <theme> [--width n]", "Print a theme's colors without applying it"},
+		"pywal":       {runPywal, "pywal [--watch] [--interval d] [--notify=false]", "Apply the colors pywal generated for the wallpaper"},
+		"random":      {runRandom, "random [--dark|--light]", "Apply a random theme"},
+		"render":      {runRender, "render <template> [--set name=value]... [--out file]", "Render a theme template"},
+		"rollback":    {runRollback, "rollback [--list] [n]", "Restore a backup of Alacritty's config"},
+		"self-update": {runSelfUpdate, "self-update [--force]", "Update alacritheme to the latest release"},
+		"sync":        {runSync, "sync pull|push [--remote url]", "Share themes with a team catalog"},
+		"toggle":      {runToggle, "toggle", "Switch between the configured light and dark theme"},
+		"transform":   {runTransform, "transform <theme> [--temperature <-1..1>] [--saturation <-1..>] [--hue <degrees>]", "Save a warmer, cooler, more vivid, muted or hue-shifted variant of a theme"},
+		"tui":         {runTUI, "tui", "Browse and preview themes (the default)"},
+		"unlock":      {runUnlock, "unlock <color>", "Let themes set a locked color again"},
+		"update":      {runUpdate, "update [--force] [--prune]", "Pull new and changed themes from where downloaded themes came from"},
+	}
+}
+
+// globalFlag is a flag accepted before the command and among its flags. set
+// takes its value, "true" for flags without an arg.
+type globalFlag struct {
+	name, short, arg, summary string
+	set                       func(value string) error
+}
+
+// globalFlags are parsed along with every command's own flags
+var globalFlags = []globalFlag{
+	{"--themes-dir", "", "dirs", fmt.Sprintf("Themes directories, separated by %q", filepath.ListSeparator), func(value string) error {
+		themesDirFlag = value
+		return nil
+	}},
+	{"--config", "", "file", "Alacritty's config file", func(value string) error {
+		configFlag = expandHome(value)
+		return nil
+	}},
+	{"--dry-run", "", "", "Print config changes as a diff instead of writing them", setBool(&dryRun)},
+	{"--offline", "", "", "Disable network features", setBool(&offline)},
+	{"--quiet", "-q", "", "Print only essential output", setLevel(quietOutput)},
+	{"--verbose", "-v", "", "Log every file read or written to stderr", setLevel(verboseOutput)},
+}
+
+// setBool sets a boolean global flag
+func setBool(p *bool) func(string) error {
+	return func(value string) error {
+		on, err := strconv.ParseBool(value)
+		*p = on
+		return err
+	}
+}
+
+// setLevel sets the output level a global flag asks for
+func setLevel(level verbosity) func(string) error {
+	return func(value string) error {
+		on, err := strconv.ParseBool(value)
+		if on {
+			outputLevel = level
+		} else if outputLevel == level {
+			outputLevel = normalOutput
+		}
+		return err
+	}
+}
+
+// addGlobalFlags defines the global flags on flags
+func addGlobalFlags(flags *flag.FlagSet) {
+	for _, f := range globalFlags {
+		names := []string{strings.TrimPrefix(f.name, "--")}
+		if f.short != "" {
+			names = append(names, strings.TrimPrefix(f.short, "-"))
+		}
+		for _, name := range names {
+			if f.arg == "" {
+				flags.BoolFunc(name, f.summary, f.set)
+			} else {
+				flags.Func(name, f.summary, f.set)
+			}
+		}
+	}
+}
+
+// parseGlobalFlags parses the global flags given before the command, and
+// returns the command and its arguments
+func parseGlobalFlags(args []string) ([]string, error) {
+	loadOffline()
+
+	flags := flag.NewFlagSet("", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	addGlobalFlags(flags)
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return []string{"help"}, nil
+	} else if err != nil {
+		return nil, usageError{reason: err.Error()}
+	}
+
+	return flags.Args(), checkPaths()
+}
+
+// usageError reports a command called with the wrong arguments, reason says
+// what was wrong if the usage alone doesn't
+type usageError struct {
+	command string
+	reason  string
+}
+
+func (e usageError) Error() string {
+	synopsis := "usage: alacritheme [flags] [command] [args], see alacritheme help"
+	if cmd, ok := commands[e.command]; ok {
+		synopsis = "usage: alacritheme " + cmd.usage
+	}
+	if e.reason == "" {
+		return synopsis
+	}
+
+	return e.reason + "\n" + synopsis
+}
+
+// usage returns the usage error of the named command
+func usage(name string) error {
+	return usageError{command: name}
+}
+
+// exitCode is the status to exit with after err
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// runCommand runs the subcommand named by args[0] with the remaining args, or
+// the default command without any
+func runCommand(args []string) error {
+	if len(args) == 0 {
+		args = []string{defaultCommand}
+	}
+	if args[0] == "--help" || args[0] == "-h" {
+		return runHelp(args[1:])
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return usageError{reason: fmt.Sprintf("unknown command %q", args[0])}
+	}
+	if wantsHelp(args[1:]) {
+		return runHelp(args[:1])
+	}
+
+	return cmd.run(args[1:])
+}
+
+// wantsHelp reports whether args ask for a command's help
+func wantsHelp(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "--help", "-h":
+			return true
+		}
+	}
+
+	return false
+}
+
+// commandNames returns the names of the commands, sorted
+func commandNames() []string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// runHelp describes alacritheme's commands and flags, or a single command:
+// help [command]
+func runHelp(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("help", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	switch len(args) {
+	case 0:
+	case 1:
+		cmd, ok := commands[args[0]]
+		if !ok {
+			return usageError{command: "help", reason: fmt.Sprintf("unknown command %q", args[0])}
+		}
+		result("usage: alacritheme %s\n\n%s.\n", cmd.usage, cmd.summary)
+		return nil
+	default:
+		return usage("help")
+	}
+
+	var b strings.Builder
+	b.WriteString("usage: alacritheme [flags] [command] [args]\n\n")
+	fmt.Fprintf(&b, "Browse, preview and apply Alacritty themes. Without a command, alacritheme runs %s.\n\n", defaultCommand)
+	b.WriteString("Commands:\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "  %-12s %s\n", name, commands[name].summary)
+	}
+	b.WriteString("\nFlags:\n")
+	for _, f := range globalFlags {
+		name := f.name
+		if f.short != "" {
+			name = f.short + ", " + name
+		}
+		if f.arg != "" {
+			name += " <" + f.arg + ">"
+		}
+		fmt.Fprintf(&b, "  %-20s %s\n", name, f.summary)
+	}
+	b.WriteString("\nRun alacritheme help <command> for a command's usage.\n")
+	result("%s", b.String())
+
+	return nil
+}
+
+// parseFlags parses flags, and the global flags, given before, after or
+// between positional arguments and returns the positional arguments, which
+// are all the arguments after "--". The flag set is named after its command,
+// which a bad flag reports the usage of.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	addGlobalFlags(flags)
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, usageError{command: flags.Name(), reason: err.Error()}
+		}
+
+		rest := flags.Args()
+		if parsed := args[:len(args)-len(rest)]; len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			return append(positional, rest...), checkPaths()
+		}
+		if len(rest) == 0 {
+			return positional, checkPaths()
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
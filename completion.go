@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completionArgs describes what a command's arguments complete to: words
+// for its first argument, then theme names if takesTheme is set
+type completionArgs struct {
+	words      []string
+	takesTheme bool
+}
+
+// commandArgs are the completions of commands that take arguments
+var commandArgs = map[string]completionArgs{
+	"apply":      {takesTheme: true},
+	"completion": {words: []string{"bash", "zsh", "fish"}},
+	"explain":    {words: []string{"apply"}, takesTheme: true},
+	"export":     {takesTheme: true},
+	"preview":    {takesTheme: true},
+	"transform":  {takesTheme: true},
+	"sync":       {words: []string{"pull", "push"}},
+	"lock":       {words: colorKeys()},
+	"unlock":     {words: colorKeys()},
+}
+
+// colorKeys returns the keys of the primary and palette colors, e.g.
+// "normal.red"
+func colorKeys() []string {
+	var keys []string
+	for _, slot := range (ColorScheme{}).slots() {
+		keys = append(keys, slot.Key)
+	}
+
+	return keys
+}
+
+// runCompletion prints the completion script for a shell:
+// completion bash|zsh|fish
+func runCompletion(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("completion", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usage("completion")
+	}
+
+	switch args[0] {
+	case "bash":
+		result("%s", bashCompletion())
+	case "zsh":
+		result("%s", zshCompletion())
+	case "fish":
+		result("%s", fishCompletion())
+	default:
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", args[0])
+	}
+
+	return nil
+}
+
+// globalFlagNames returns the long names of the global flags
+func globalFlagNames() []string {
+	var names []string
+	for _, f := range globalFlags {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// argCommands returns the commands with argument completions in a stable
+// order, so the generated scripts don't change between runs
+func argCommands() []string {
+	var names []string
+	for name := range commandArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString("# alacritheme completion for bash, load with: source <(alacritheme completion bash)\n")
+	b.WriteString("_alacritheme() {\n")
+	b.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]} cmd= pos=0 i\n")
+	b.WriteString("    # Find the command and the position of the current word after it, skipping global flags\n")
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("        case ${COMP_WORDS[i]} in\n")
+	b.WriteString("            --themes-dir|--config) ((i++)) ;;\n")
+	b.WriteString("            -*) ;;\n")
+	b.WriteString("            *) if [[ -z $cmd ]]; then cmd=${COMP_WORDS[i]}; else ((pos++)); fi ;;\n")
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n")
+	b.WriteString("    case ${COMP_WORDS[COMP_CWORD-1]} in\n")
+	b.WriteString("        --themes-dir) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n")
+	b.WriteString("        --config) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n")
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(append(commandNames(), globalFlagNames()...), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $cmd in\n")
+	for _, name := range argCommands() {
+		args := commandArgs[name]
+		themes := `$(alacritheme list 2>/dev/null)`
+		switch {
+		case len(args.words) > 0 && args.takesTheme:
+			fmt.Fprintf(&b, "        %s) if ((pos == 0)); then COMPREPLY=($(compgen -W %q -- \"$cur\")); else COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); fi ;;\n", name, strings.Join(args.words, " "), themes)
+		case len(args.words) > 0:
+			fmt.Fprintf(&b, "        %s) ((pos == 0)) && COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", name, strings.Join(args.words, " "))
+		default:
+			fmt.Fprintf(&b, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", name, themes)
+		}
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -F _alacritheme alacritheme\n")
+
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString("#compdef alacritheme\n")
+	b.WriteString("# alacritheme completion for zsh, load with: source <(alacritheme completion zsh)\n")
+	b.WriteString("_alacritheme() {\n")
+	b.WriteString("    local cmd i pos=0\n")
+	b.WriteString("    # Find the command and the position of the current word after it, skipping global flags\n")
+	b.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("        case $words[i] in\n")
+	b.WriteString("            --themes-dir|--config) ((i++)) ;;\n")
+	b.WriteString("            -*) ;;\n")
+	b.WriteString("            *) if [[ -z $cmd ]]; then cmd=$words[i]; else ((pos++)); fi ;;\n")
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n")
+	b.WriteString("    case $words[CURRENT-1] in\n")
+	b.WriteString("        --themes-dir) _directories; return ;;\n")
+	b.WriteString("        --config) _files; return ;;\n")
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ -z $cmd ]]; then\n")
+	fmt.Fprintf(&b, "        compadd -- %s\n", strings.Join(append(commandNames(), globalFlagNames()...), " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $cmd in\n")
+	for _, name := range argCommands() {
+		args := commandArgs[name]
+		themes := `compadd -- ${(f)"$(alacritheme list 2>/dev/null)"}`
+		switch {
+		case len(args.words) > 0 && args.takesTheme:
+			fmt.Fprintf(&b, "        %s) if ((pos == 0)); then compadd -- %s; else %s; fi ;;\n", name, strings.Join(args.words, " "), themes)
+		case len(args.words) > 0:
+			fmt.Fprintf(&b, "        %s) ((pos == 0)) && compadd -- %s ;;\n", name, strings.Join(args.words, " "))
+		default:
+			fmt.Fprintf(&b, "        %s) %s ;;\n", name, themes)
+		}
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	b.WriteString("compdef _alacritheme alacritheme\n")
+
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# alacritheme completion for fish, load with: alacritheme completion fish | source\n")
+	b.WriteString("complete -c alacritheme -f\n")
+	b.WriteString("complete -c alacritheme -l themes-dir -r -a '(__fish_complete_directories)' -d 'Themes directory'\n")
+	b.WriteString("complete -c alacritheme -l config -r -F -d 'Alacritty config'\n")
+	b.WriteString("complete -c alacritheme -l dry-run -d 'Print config changes instead of writing them'\n")
+	b.WriteString("complete -c alacritheme -l offline -d 'Disable network features'\n")
+	b.WriteString("complete -c alacritheme -s q -l quiet -d 'Print only essential output'\n")
+	b.WriteString("complete -c alacritheme -s v -l verbose -d 'Log file operations'\n")
+	fmt.Fprintf(&b, "complete -c alacritheme -n __fish_use_subcommand -a %q\n", strings.Join(commandNames(), " "))
+	for _, name := range argCommands() {
+		args := commandArgs[name]
+		seen := "__fish_seen_subcommand_from " + name
+		themes := "(alacritheme list 2>/dev/null)"
+		if len(args.words) > 0 {
+			words := strings.Join(args.words, " ")
+			fmt.Fprintf(&b, "complete -c alacritheme -n '%s; and not __fish_seen_subcommand_from %s' -a %q\n", seen, words, words)
+			if args.takesTheme {
+				fmt.Fprintf(&b, "complete -c alacritheme -n '%s; and __fish_seen_subcommand_from %s' -a '%s'\n", seen, words, themes)
+			}
+		} else {
+			fmt.Fprintf(&b, "complete -c alacritheme -n '%s' -a '%s'\n", seen, themes)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// dryRun shows changes to the Alacritty config as a diff instead of
+// writing them
+var dryRun bool
+
+// applyOptions is how themes are written into Alacritty's config
+type applyOptions struct {
+	locks map[string]string
+	// mode is how the theme gets into the config, one of the apply modes
+	mode string
+	// liveReload turns live_config_reload on where the config turns it off,
+	// so Alacritty shows previews. Themes that are kept leave it as it was.
+	liveReload bool
+}
+
+// applyTheme returns the content of the config at configFile with the theme
+// at themePath applied and the locked colors set
+func applyTheme(content []byte, configFile, themePath string, opts applyOptions) ([]byte, error) {
+	var config map[string]interface{}
+	if err := toml.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	return configWithTheme(content, config, configFile, themePath, opts)
+}
+
+// The comments around the colors inline mode copies into the config
+const (
+	inlineBegin = "# alacritheme: begin inlined theme, replaced on every apply"
+	inlineEnd   = "# alacritheme: end inlined theme"
+)
+
+// configWithTheme returns the content of the config at configFile, already
+// parsed as config, with the theme at themePath applied and the locked colors
+// set, leaving config unchanged. The theme import
+// replaces the one from before, or is added after the other imports.
+// Inlining replaces the colors between inlineBegin and inlineEnd with the
+// theme's instead, and drops the theme import. It fails for configs with
+// colors of their own, which it would replace.
+//
+// Only the changed keys are edited, keeping the rest of the file as it was.
+// Configs laid out in ways the editor doesn't follow, where the edit
+// wouldn't result in the intended config, are reported rather than encoded
+// anew.
+func configWithTheme(content []byte, config map[string]interface{}, configFile, themePath string, opts applyOptions) ([]byte, error) {
+	updated := make(map[string]interface{}, len(config)+2)
+	for k, v := range config {
+		updated[k] = v
+	}
+
+	if opts.mode == applyInline {
+		theme, err := os.ReadFile(themePath)
+		if err != nil {
+			return nil, err
+		}
+		var parsed map[string]interface{}
+		if err := toml.Unmarshal(theme, &parsed); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(themePath), err)
+		}
+		if colors, ok := parsed["colors"]; ok {
+			updated["colors"] = colors
+		} else {
+			delete(updated, "colors")
+		}
+	}
+	if opts.mode != applyInclude {
+		// The include file sets them otherwise
+		updated = withLocks(updated, opts.locks)
+	}
+
+	// Keys may move between the top level and general, both are copied
+	if general, ok := updated["general"].(map[string]interface{}); ok {
+		updated["general"] = copyTable(general)
+	}
+	section := importSection(config)
+	target, stale := updated, (map[string]interface{})(nil)
+	prefix, stalePrefix := "", "general."
+	if general, ok := updated["general"].(map[string]interface{}); ok {
+		stale = general
+	}
+	if section == "general" {
+		if stale == nil {
+			updated["general"] = make(map[string]interface{})
+		}
+		target, stale = updated["general"].(map[string]interface{}), updated
+		prefix, stalePrefix = "general.", ""
+	}
+
+	// Keys left where the installed Alacritty doesn't read them, e.g. from
+	// before it was upgraded, move to where it does
+	moved := make(map[string]bool)
+	for _, key := range []string{"import", "live_config_reload"} {
+		value, ok := stale[key]
+		if !ok {
+			continue
+		}
+		trace("moving %s%s to %s%s", stalePrefix, key, prefix, key)
+		delete(stale, key)
+		moved[key] = true
+		if key == "import" {
+			existing, _ := target[key].([]interface{})
+			old, _ := value.([]interface{})
+			target[key] = mergeImports(old, existing)
+		} else if _, ok := target[key]; !ok {
+			target[key] = value
+		}
+	}
+	general, _ := updated["general"].(map[string]interface{})
+	dropGeneral := section == "" && general != nil && len(general) == 0 && len(moved) > 0
+	if dropGeneral {
+		delete(updated, "general")
+	}
+
+	if imports := withThemeImport(target["import"], configFile, themePath, opts.mode); len(imports) > 0 {
+		target["import"] = imports
+	} else {
+		delete(target, "import")
+	}
+	setReload := moved["live_config_reload"]
+	if reload, ok := target["live_config_reload"].(bool); ok && !reload && opts.liveReload {
+		target["live_config_reload"] = true
+		setReload = true
+	}
+	if general, ok := updated["general"].(map[string]interface{}); ok && len(general) == 0 && config["general"] == nil {
+		// Made for an import that inlining dropped
+		delete(updated, "general")
+	}
+
+	encoded, err := encodeConfig(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := parseTomlDoc(content)
+	for key := range moved {
+		if err := doc.remove(stalePrefix + key); err != nil {
+			return nil, err
+		}
+	}
+	if dropGeneral {
+		doc.removeTable("general")
+	}
+	if reload, ok := target["live_config_reload"]; ok && setReload {
+		if err := doc.set(prefix+"live_config_reload", reload); err != nil {
+			return nil, err
+		}
+	}
+	if imports, ok := target["import"]; ok {
+		err = doc.set(prefix+"import", imports)
+	} else {
+		err = doc.remove(prefix + "import")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.mode {
+	case applyInline:
+		// Only the colors between the markers are alacritheme's to replace
+		var own map[string]interface{}
+		if err := toml.Unmarshal(doc.withoutBlock(inlineBegin, inlineEnd).bytes(), &own); err == nil && own["colors"] != nil {
+			return nil, fmt.Errorf("%s sets colors of its own, which inline mode would replace: move them into a theme, or between %q and %q to have them replaced", configFile, inlineBegin, inlineEnd)
+		}
+		var block []byte
+		if colors, ok := updated["colors"]; ok {
+			if block, err = encodeConfig(map[string]interface{}{"colors": colors}); err != nil {
+				return nil, err
+			}
+		}
+		doc.replaceBlock(inlineBegin, inlineEnd, block)
+	case applyByImport:
+		keys := make([]string, 0, len(opts.locks))
+		for key := range opts.locks {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := doc.set("colors."+key, opts.locks[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	edited := doc.bytes()
+	if !sameConfig(edited, encoded) {
+		// Rewriting all of it would lose the comments and layout
+		return nil, fmt.Errorf("couldn't edit %s in place, apply the theme by hand or simplify the config's layout", configFile)
+	}
+	return edited, nil
+}
+
+// installedAlacritty returns the output of alacritty --version, run once
+var installedAlacritty = sync.OnceValues(func() (string, error) {
+	trace("running alacritty --version")
+	out, err := exec.Command("alacritty", "--version").Output()
+	return strings.TrimSpace(string(out)), err
+})
+
+// parseAlacrittyVersion returns the major and minor version of an
+// alacritty --version output, e.g. "alacritty 0.14.0 (22a4475)"
+func parseAlacrittyVersion(version string) (major, minor int, ok bool) {
+	_, err := fmt.Sscanf(strings.TrimPrefix(version, "alacritty "), "%d.%d", &major, &minor)
+	return major, minor, err == nil
+}
+
+// importSection returns the table the config's import and live_config_reload
+// belong in: general for Alacritty 0.14 and newer, which deprecated them at
+// the top level, and the top level, "", for older versions, which don't know
+// general. Without an Alacritty to ask, it's wherever the config has them.
+func importSection(config map[string]interface{}) string {
+	if version, err := installedAlacritty(); err == nil {
+		if major, minor, ok := parseAlacrittyVersion(version); ok {
+			if major == 0 && minor < 14 {
+				return ""
+			}
+			return "general"
+		}
+	}
+
+	if _, ok := config["general"].(map[string]interface{}); ok {
+		return "general"
+	}
+	return ""
+}
+
+// reloadOff reports whether the config turns live_config_reload off, which
+// Alacritty has on by default, and the key it's turned off by
+func reloadOff(config map[string]interface{}) (string, bool) {
+	if general, ok := config["general"].(map[string]interface{}); ok {
+		if reload, ok := general["live_config_reload"].(bool); ok {
+			return "general.live_config_reload", !reload
+		}
+	}
+
+	reload, ok := config["live_config_reload"].(bool)
+	return "live_config_reload", ok && !reload
+}
+
+// encodeConfig encodes a config the way alacritheme writes them
+func encodeConfig(config map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sameConfig reports whether two configs hold the same settings, however
+// they're laid out
+func sameConfig(a, b []byte) bool {
+	var configA, configB map[string]interface{}
+	if toml.Unmarshal(a, &configA) != nil || toml.Unmarshal(b, &configB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(configA, configB)
+}
+
+// mergeImports returns the imports of both lists, first first, without
+// duplicates
+func mergeImports(first, second []interface{}) []interface{} {
+	seen := make(map[string]bool)
+	var merged []interface{}
+	for _, imp := range append(append([]interface{}(nil), first...), second...) {
+		if path, ok := imp.(string); ok {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+		}
+		merged = append(merged, imp)
+	}
+
+	return merged
+}
+
+// withThemeImport returns the imports of the config at configFile with its
+// theme import replaced by themePath, or dropped when inlining. The include
+// mode replaces it by the include file instead, unless that's imported
+// already.
+func withThemeImport(value interface{}, configFile, themePath, mode string) []interface{} {
+	existing, _ := value.([]interface{})
+	imports := make([]interface{}, 0, len(existing)+1)
+	var paths []string
+	for _, imp := range existing {
+		// Anything but paths is left for Alacritty to report
+		if path, ok := imp.(string); ok {
+			imports = append(imports, imp)
+			paths = append(paths, expandHome(path))
+		}
+	}
+
+	i := themeImport(paths, configFile)
+	if mode == applyInclude {
+		themePath = includeFile(configFile)
+		for _, path := range paths {
+			if resolveImport(path, configFile) == themePath {
+				mode = applyInline
+			}
+		}
+	}
+	switch {
+	case mode == applyInline && i >= 0:
+		return append(imports[:i], imports[i+1:]...)
+	case mode == applyInline:
+		return imports
+	case i >= 0:
+		imports[i] = themePath
+		return imports
+	default:
+		return append(imports, themePath)
+	}
+}
+
+// includeFile returns the file the include mode imports themes in, next to
+// the config at configFile
+func includeFile(configFile string) string {
+	return resolveImport("alacritheme.toml", configFile)
+}
+
+// includeWithTheme returns the content of the include file of the config at
+// configFile, already parsed as config, importing the theme at themePath with
+// the locked colors
+func includeWithTheme(config map[string]interface{}, themePath string, opts applyOptions) ([]byte, error) {
+	include := make(map[string]interface{})
+	if importSection(config) == "general" {
+		include["general"] = map[string]interface{}{"import": []string{themePath}}
+	} else {
+		include["import"] = []string{themePath}
+	}
+
+	encoded, err := encodeConfig(withLocks(include, opts.locks))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("# Generated by alacritheme, which rewrites it for every theme applied\n\n"), encoded...), nil
+}
+
+// themeFiles returns the files applying the theme at themePath to the config
+// at configFile, already parsed as config, writes: the config, and in the
+// include mode the include file before it, so the config never imports a
+// missing file. A file Alacritty couldn't read is an error instead.
+func themeFiles(content []byte, config map[string]interface{}, configFile, themePath string, opts applyOptions) ([]generatedFile, error) {
+	updated, err := configWithTheme(content, config, configFile, themePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	files := []generatedFile{{configFile, updated}}
+	if opts.mode == applyInclude {
+		include, err := includeWithTheme(config, themePath, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append([]generatedFile{{includeFile(configFile), include}}, files...)
+	}
+
+	for _, file := range files {
+		if err := validateConfig(file.content, file.path, config, files); err != nil {
+			return nil, fmt.Errorf("not writing %s, Alacritty couldn't read it: %w", file.path, err)
+		}
+	}
+	return files, nil
+}
+
+// readGenerated returns the files applying a theme to the config at
+// configFile writes, with their current content, leaving out the ones that
+// don't exist, to put them back later
+func readGenerated(configFile string) []generatedFile {
+	var files []generatedFile
+	for _, path := range []string{includeFile(configFile), configFile} {
+		if content, err := os.ReadFile(path); err == nil {
+			files = append(files, generatedFile{path, content})
+		}
+	}
+
+	return files
+}
+
+// inlinedFile records the theme last inlined into each Alacritty config, by
+// the config's path, as the config doesn't name it
+const inlinedFile = "inlined.toml"
+
+// inlinedTheme returns the theme last inlined into configFile, if any
+func inlinedTheme(configFile string) string {
+	configFile, _ = filepath.Abs(configFile)
+	var inlined map[string]string
+	if err := loadState(inlinedFile, &inlined); err != nil {
+		trace("reading %s: %v", inlinedFile, err)
+	}
+
+	return inlined[configFile]
+}
+
+// saveInlined records theme as inlined into configFile, or forgets about
+// configFile when theme is empty
+func saveInlined(configFile, theme string) error {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return err
+	}
+
+	inlined := make(map[string]string)
+	if err := loadState(inlinedFile, &inlined); err != nil {
+		return err
+	}
+
+	if theme == "" {
+		delete(inlined, configFile)
+	} else {
+		inlined[configFile] = theme
+	}
+	return saveState(inlinedFile, inlined)
+}
+
+// appliedTheme reads the Alacritty config at path and returns the theme it
+// imports, resolved against the config's directory like Alacritty does, and
+// the config's content. A config without a theme import has the theme last
+// inlined into it, and a missing config has none.
+func appliedTheme(path string) (string, []byte, error) {
+	if err := checkLegacyConfig(path); err != nil {
+		return "", nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, err
+	}
+
+	var config map[string]interface{}
+	if err := toml.Unmarshal(content, &config); err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	theme := importedTheme(config, path)
+	if theme != "" && !filepath.IsAbs(theme) {
+		theme = filepath.Join(filepath.Dir(path), theme)
+	} else if theme == "" {
+		theme = inlinedTheme(path)
+	}
+
+	return theme, content, nil
+}
+
+// setTheme backs up the Alacritty config at path, whose current content is
+// given, and rewrites it to apply the theme at themePath with the locked
+// colors, syncs tmux's colors when sync.tmux is set and runs the scripts'
+// theme_applied handlers. It fails while the TUI previews themes on the
+// config.
+func setTheme(path string, content []byte, themePath string) error {
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockInstance(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var config map[string]interface{}
+	if err := toml.Unmarshal(content, &config); err != nil {
+		return err
+	}
+	opts := cfg.applyOptions()
+	files, err := themeFiles(content, config, path, themePath, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := saveBackup(path, content, cfg.Backups.Keep); err != nil {
+		return err
+	}
+	if err := writeGenerated(files); err != nil {
+		return err
+	}
+	if opts.mode == applyInline {
+		if err := saveInlined(path, themePath); err != nil {
+			return err
+		}
+	}
+	if cfg.Sync.Tmux {
+		if err := syncTmux(themePath); err != nil {
+			return err
+		}
+	}
+
+	scripts, err := loadedScripts()
+	if err != nil {
+		return err
+	}
+	return scripts.emit(themeAppliedEvent, themePath)
+}
+
+// writeConfig replaces the config file atomically, so Alacritty never reloads
+// a half-written file and a crash never leaves one behind: the content is
+// synced to disk before it's renamed over the config. Symlinks, e.g. from a
+// dotfiles manager, are followed and the file's mode is kept. A file that
+// already has that content is left alone, so Alacritty doesn't reload it.
+func writeConfig(path string, content []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		trace("%s is unchanged", path)
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a rename in dir to disk. Not every system can sync a
+// directory, e.g. Windows, where it's left to the file system.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		trace("syncing %s: %v", dir, err)
+	}
+}
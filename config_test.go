@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigWithTheme(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"old.toml":  "[colors.primary]\nbackground = '#ffffff'\nforeground = '#000000'\n",
+		"new.toml":  "[colors.primary]\nbackground = '#000000'\nforeground = '#ffffff'\n",
+		"keys.toml": "[keyboard]\nbindings = []\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		mode    string
+		locks   map[string]string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "import replaces the theme import",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			in:      "[general]\nimport = ['$DIR/keys.toml', '$DIR/old.toml'] # themes\n\n[font]\nsize = 12\n",
+			want:    "[general]\nimport = ['$DIR/keys.toml', '$DIR/new.toml'] # themes\n\n[font]\nsize = 12\n",
+		},
+		{
+			name:    "import adds the theme after the other imports",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			in:      "[general]\nimport = ['$DIR/keys.toml']\n",
+			want:    "[general]\nimport = ['$DIR/keys.toml', '$DIR/new.toml']\n",
+		},
+		{
+			name:    "import moves a top-level import below general for 0.14",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			in:      "import = ['$DIR/old.toml']\n\n[font]\nsize = 12\n",
+			want:    "[font]\nsize = 12\n\n[general]\nimport = ['$DIR/new.toml']\n",
+		},
+		{
+			name:    "import moves the import out of general for 0.13",
+			version: "alacritty 0.13.2",
+			mode:    applyByImport,
+			in:      "[general]\nimport = ['$DIR/old.toml']\n\n[font]\nsize = 12\n",
+			want:    "import = ['$DIR/new.toml']\n\n[font]\nsize = 12\n",
+		},
+		{
+			name:    "import sets the locked colors",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			locks:   map[string]string{"primary.background": "#282a36"},
+			in:      "[general]\nimport = ['$DIR/old.toml']\n",
+			want:    "[general]\nimport = ['$DIR/new.toml']\n\n[colors.primary]\nbackground = '#282a36'\n",
+		},
+		{
+			name:    "import turns live_config_reload on",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			in:      "[general]\nlive_config_reload = false\nimport = ['$DIR/old.toml']\n",
+			want:    "[general]\nlive_config_reload = true\nimport = ['$DIR/new.toml']\n",
+		},
+		{
+			name:    "include imports the include file in place of the theme",
+			version: "alacritty 0.14.0",
+			mode:    applyInclude,
+			in:      "[general]\nimport = ['$DIR/keys.toml', '$DIR/old.toml']\n",
+			want:    "[general]\nimport = ['$DIR/keys.toml', '$DIR/alacritheme.toml']\n",
+		},
+		{
+			name:    "include leaves a config importing the include file",
+			version: "alacritty 0.14.0",
+			mode:    applyInclude,
+			in:      "[general]\nimport = ['alacritheme.toml']\n",
+			want:    "[general]\nimport = ['alacritheme.toml']\n",
+		},
+		{
+			name:    "inline copies the colors between the markers",
+			version: "alacritty 0.14.0",
+			mode:    applyInline,
+			in:      "[general]\nimport = ['$DIR/keys.toml', '$DIR/old.toml']\n",
+			want:    "[general]\nimport = ['$DIR/keys.toml']\n\n" + inlineBegin + "\n[colors]\n  [colors.primary]\n    background = '#000000'\n    foreground = '#ffffff'\n" + inlineEnd + "\n",
+		},
+		{
+			name:    "inline replaces the colors between the markers",
+			version: "alacritty 0.14.0",
+			mode:    applyInline,
+			in:      "[font]\nsize = 12\n\n" + inlineBegin + "\n[colors.primary]\nbackground = '#ffffff'\n" + inlineEnd + "\n\n[cursor]\nstyle = 'Beam'\n",
+			want:    "[font]\nsize = 12\n\n" + inlineBegin + "\n[colors]\n  [colors.primary]\n    background = '#000000'\n    foreground = '#ffffff'\n" + inlineEnd + "\n\n[cursor]\nstyle = 'Beam'\n",
+		},
+		{
+			name:    "inline refuses colors of the config's own",
+			version: "alacritty 0.14.0",
+			mode:    applyInline,
+			in:      "[colors.primary]\nbackground = '#ffffff'\n",
+			wantErr: "sets colors of its own",
+		},
+		{
+			name:    "an inline general table is reported",
+			version: "alacritty 0.14.0",
+			mode:    applyByImport,
+			in:      "general = { import = ['$DIR/old.toml'] }\n",
+			wantErr: "inline table",
+		},
+	}
+
+	configFile := filepath.Join(dir, "alacritty.toml")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(was func() (string, error)) { installedAlacritty = was }(installedAlacritty)
+			installedAlacritty = func() (string, error) { return tt.version, nil }
+
+			in := strings.ReplaceAll(tt.in, "$DIR", dir)
+			got, err := applyTheme([]byte(in), configFile, filepath.Join(dir, "new.toml"), applyOptions{mode: tt.mode, locks: tt.locks, liveReload: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.ReplaceAll(tt.want, "$DIR", dir); string(got) != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
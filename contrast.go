@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// minContrast is the WCAG AA contrast ratio for normal text
+const minContrast = 4.5
+
+// contrastIssue is a color that is hard to read on the theme's background,
+// together with the closest color that reaches minContrast
+type contrastIssue struct {
+	slot       colorSlot
+	ratio      float64
+	suggestion string
+	fixedRatio float64
+}
+
+// relativeLuminance implements the WCAG 2 relative luminance of a color
+func relativeLuminance(c colorful.Color) float64 {
+	r, g, b := c.LinearRgb()
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// contrastRatio implements the WCAG 2 contrast ratio between two colors
+func contrastRatio(a, b colorful.Color) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// fixContrast returns the color with the smallest lightness change, keeping
+// hue and chroma, that reaches minContrast against bg
+func fixContrast(c, bg colorful.Color) colorful.Color {
+	h, chroma, l := c.Hcl()
+
+	// Move away from the background's lightness
+	target := 1.0
+	if relativeLuminance(bg) > 0.18 {
+		target = 0
+	}
+
+	candidate := func(l float64) colorful.Color {
+		return colorful.Hcl(h, chroma, l).Clamped()
+	}
+
+	if contrastRatio(candidate(target), bg) < minContrast {
+		// Not reachable with this hue, fall back to black or white
+		return colorful.Color{R: target, G: target, B: target}
+	}
+
+	lo, hi := l, target
+	for i := 0; i < 24; i++ {
+		mid := (lo + hi) / 2
+		if contrastRatio(candidate(mid), bg) >= minContrast {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	return candidate(hi)
+}
+
+// contrastIssues checks the foreground and palette colors against the
+// background. The slot closest to the background by convention (black on
+// dark themes, white on light ones) is expected to be low contrast and skipped.
+func contrastIssues(scheme ColorScheme) ([]contrastIssue, error) {
+	bg, err := parseColor(scheme.Colors.Primary.Background)
+	if err != nil {
+		return nil, fmt.Errorf("invalid background color: %w", err)
+	}
+
+	skip := "normal.black"
+	if relativeLuminance(bg) > 0.18 {
+		skip = "normal.white"
+	}
+
+	var issues []contrastIssue
+	for _, slot := range scheme.slots() {
+		if slot.Key == "primary.background" || slot.Key == skip || slot.Color == "" {
+			continue
+		}
+
+		c, err := parseColor(slot.Color)
+		if err != nil {
+			continue
+		}
+
+		ratio := contrastRatio(c, bg)
+		if ratio >= minContrast {
+			continue
+		}
+
+		fixed := fixContrast(c, bg)
+		issues = append(issues, contrastIssue{
+			slot:       slot,
+			ratio:      ratio,
+			suggestion: fixed.Hex(),
+			fixedRatio: contrastRatio(fixed, bg),
+		})
+	}
+
+	return issues, nil
+}
+
+// renderContrastReport lists the unreadable colors with their suggested fixes
+func renderContrastReport(name string, issues []contrastIssue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Contrast of %s (WCAG AA, %.1f:1)\n\n", name, minContrast)
+
+	if len(issues) == 0 {
+		b.WriteString("All colors are readable on the background.\n")
+		return b.String()
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "%-15s %s %s %4.1f:1  →  %s %s %4.1f:1\n",
+			issue.slot.Label,
+			swatch(issue.slot.Color), issue.slot.Color, issue.ratio,
+			swatch(issue.suggestion), issue.suggestion, issue.fixedRatio,
+		)
+	}
+	b.WriteString("\nPress F to save a variant with the suggested colors.\n")
+
+	return b.String()
+}
+
+func swatch(color string) string {
+	return lipgloss.NewStyle().Background(lipgloss.Color(color)).Render("  ")
+}
+
+// saveFixedVariant writes a copy of the theme at path with the suggested
+// colors applied next to base and returns the new file's path
+func saveFixedVariant(path, base string, issues []contrastIssue) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		return "", err
+	}
+
+	for _, issue := range issues {
+		setColor(theme, issue.slot.Key, issue.suggestion)
+	}
+
+	return writeVariant(base, "contrast", theme)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isSchemeFile reports whether path has the extension of a scheme of another
+// terminal that's converted when listed
+func isSchemeFile(path string) bool {
+	return len(formatsFor(strings.ToLower(filepath.Ext(path)), true)) > 0
+}
+
+// convertedRoot is where schemes of other terminals are converted to, as
+// Alacritty can only import TOML themes
+func convertedRoot() (string, error) {
+	state, err := appStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(state, "converted"), nil
+}
+
+// convertScheme converts the scheme at path into a theme and returns its
+// path. The theme mirrors the scheme's absolute path below convertedRoot and
+// is only rewritten when the scheme changed.
+func convertScheme(path string) (string, error) {
+	return convertFile(path, formatsFor(strings.ToLower(filepath.Ext(path)), true))
+}
+
+// convertFile is convertScheme for a scheme in one of formats
+func convertFile(path string, formats []importFormat) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Formats sharing an extension, e.g. base16 and Alacritty's own YAML,
+	// are told apart by which one reads a complete scheme
+	var colors map[string]string
+	err = fmt.Errorf("%s is not a scheme alacritheme can convert", path)
+	for _, format := range formats {
+		parsed, parseErr := format.parse(content)
+		if parseErr != nil {
+			err = fmt.Errorf("%s: %w", path, parseErr)
+			continue
+		}
+		if missing := missingSlot(parsed); missing != "" {
+			err = fmt.Errorf("%s is not a complete scheme, it has no color for %s", path, missing)
+			continue
+		}
+		colors, err = parsed, nil
+		break
+	}
+	if err != nil {
+		return "", err
+	}
+
+	theme, err := encodeColors(colors)
+	if err != nil {
+		return "", err
+	}
+	theme = append([]byte(fmt.Sprintf("# Converted from %s by alacritheme\n", filepath.Base(path))), theme...)
+
+	root, err := convertedRoot()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(root, strings.TrimPrefix(abs, filepath.VolumeName(abs))+".toml")
+	if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, theme) {
+		return target, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	trace("writing %s", target)
+	return target, os.WriteFile(target, theme, 0644)
+}
+
+// convertedSource returns the scheme the theme at path was converted from,
+// or an empty string when it wasn't
+func convertedSource(path string) string {
+	root, err := convertedRoot()
+	if err != nil {
+		return ""
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	source := strings.TrimSuffix(string(filepath.Separator)+rel, ".toml")
+	if volume := filepath.VolumeName(path); volume != "" {
+		// The volume of the scheme isn't recorded, assume the theme's
+		source = volume + source
+	}
+	return source
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// unifiedDiff returns a unified diff turning a into b, or an empty string if
+// they are equal
+func unifiedDiff(nameA, nameB, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for i, prevEnd := 0, 0; i < len(lines); {
+		// Skip to the next change
+		for i < len(lines) && lines[i].op == ' ' {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		// Extend the hunk over changes separated by only a few unchanged lines
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+
+		from := max(i-diffContext, prevEnd)
+		to := min(end+diffContext, len(lines))
+		writeHunk(&out, lines, from, to)
+		i, prevEnd = to, to
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, lines []diffLine, from, to int) {
+	// Line numbers in a and b where the hunk starts
+	lineA, lineB := 1, 1
+	for _, l := range lines[:from] {
+		if l.op != '+' {
+			lineA++
+		}
+		if l.op != '-' {
+			lineB++
+		}
+	}
+
+	countA, countB := 0, 0
+	for _, l := range lines[from:to] {
+		if l.op != '+' {
+			countA++
+		}
+		if l.op != '-' {
+			countB++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+	for _, l := range lines[from:to] {
+		fmt.Fprintf(out, "%c%s\n", l.op, l.text)
+	}
+}
+
+// diffLines computes a line diff from the longest common subsequence of a and b
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
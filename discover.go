@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// alacrittyConfigCandidates returns where Alacritty looks for its config on
+// this platform, in the order it looks. The system-wide /etc/alacritty is
+// left out, alacritheme shouldn't rewrite it.
+func alacrittyConfigCandidates() []string {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return []string{filepath.Join(dir, "alacritty", "alacritty.toml")}
+		}
+		return nil
+	}
+
+	var candidates []string
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		candidates = append(candidates,
+			filepath.Join(dir, "alacritty", "alacritty.toml"),
+			filepath.Join(dir, "alacritty.toml"))
+	}
+
+	return append(candidates,
+		expandHome("~/.config/alacritty/alacritty.toml"),
+		expandHome("~/.alacritty.toml"))
+}
+
+// discoverConfig returns the Alacritty config Alacritty itself would read,
+// or where it would look first when there's none yet
+func discoverConfig() string {
+	candidates := alacrittyConfigCandidates()
+	for _, path := range candidates {
+		if fileExists(path) {
+			return path
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// alacrittyDir is Alacritty's config directory, where themes are usually
+// kept next to the config
+func alacrittyDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "alacritty")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "alacritty")
+	}
+
+	return expandHome("~/.config/alacritty")
+}
+
+// legacyConfig returns the YAML config Alacritty before 0.13 read in place of
+// configFile: configFile itself when it's YAML, or alacritty.yml next to a
+// missing alacritty.toml
+func legacyConfig(configFile string) (string, bool) {
+	switch filepath.Ext(configFile) {
+	case ".yml", ".yaml":
+		return configFile, true
+	case ".toml":
+		if fileExists(configFile) {
+			return "", false
+		}
+		base := strings.TrimSuffix(configFile, ".toml")
+		for _, ext := range []string{".yml", ".yaml"} {
+			if fileExists(base + ext) {
+				return base + ext, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// checkLegacyConfig fails for a configFile that's in YAML, which alacritheme
+// can't edit, rather than creating a TOML config that hides it
+func checkLegacyConfig(configFile string) error {
+	if legacy, ok := legacyConfig(configFile); ok {
+		return fmt.Errorf("%s is a YAML config, which alacritheme can't edit, run alacritheme migrate to convert it to TOML", legacy)
+	}
+
+	return nil
+}
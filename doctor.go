@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// doctor collects the outcome of runDoctor's checks
+type doctor struct {
+	problems int
+}
+
+func (d *doctor) pass(format string, a ...interface{}) {
+	result("  ✓ "+format+"\n", a...)
+}
+
+// warn reports something that works but likely isn't intended
+func (d *doctor) warn(fix, format string, a ...interface{}) {
+	result("  ! "+format+"\n", a...)
+	result("    %s\n", fix)
+}
+
+// fail reports something that keeps alacritheme from working, with a fix
+func (d *doctor) fail(fix, format string, a ...interface{}) {
+	d.problems++
+	result("  ✗ "+format+"\n", a...)
+	result("    %s\n", fix)
+}
+
+// runDoctor checks the environment alacritheme depends on and suggests fixes
+// for what's wrong: doctor
+func runDoctor(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("doctor", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usage("doctor")
+	}
+
+	d := &doctor{}
+	cfg, exists, err := loadAppConfig()
+	result("alacritheme config\n")
+	switch {
+	case err != nil:
+		d.fail("Fix the syntax error, or move the file away to start over.", "%v", err)
+	case !exists:
+		d.pass("no config file, using defaults")
+	default:
+		if err := cfg.validate(); err != nil {
+			d.fail("Fix the setting in alacritheme's config.", "%v", err)
+		} else {
+			d.pass("config is valid")
+		}
+	}
+
+	result("\nThemes\n")
+	dirs, _ := resolveThemeDirs()
+	if len(dirs) == 0 {
+		d.fail("Pass --themes-dir, set THEMES_DIR or run alacritheme once for the setup wizard.", "no themes directory configured")
+	}
+	for _, dir := range dirs {
+		d.checkThemesDir(dir)
+	}
+
+	result("\nAlacritty config\n")
+	_, configFile, _ := resolvePaths()
+	config := d.checkConfig(configFile)
+
+	result("\nAlacritty\n")
+	d.checkAlacritty(config)
+
+	result("\nBackups\n")
+	if dir, err := backupsDir(); err != nil {
+		d.fail("Set XDG_STATE_HOME to a writable directory.", "can't locate the backups directory: %v", err)
+	} else {
+		d.checkWritable(dir, "backups directory")
+	}
+
+	switch d.problems {
+	case 0:
+		info("\nNo problems found.\n")
+		return nil
+	case 1:
+		return fmt.Errorf("found 1 problem")
+	default:
+		return fmt.Errorf("found %d problems", d.problems)
+	}
+}
+
+// checkThemesDir checks that dir exists and that its themes parse
+func (d *doctor) checkThemesDir(dir string) {
+	themes, err := collectThemes(dir)
+	if os.IsNotExist(err) {
+		d.fail("Create it, or point --themes-dir or THEMES_DIR at your themes.", "%s does not exist", dir)
+		return
+	} else if err != nil {
+		d.fail("Check the directory's permissions.", "can't read %s: %v", dir, err)
+		return
+	}
+	if len(themes) == 0 {
+		d.warn("Download themes with the setup wizard, sync pull or import, or check .alacrithemeignore.", "%s contains no themes", dir)
+		return
+	}
+
+	var invalid []string
+	for _, rel := range themes {
+		trace("reading %s", filepath.Join(dir, rel))
+		if _, err := readScheme(filepath.Join(dir, rel)); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", rel, err))
+		}
+	}
+	if len(invalid) == 0 {
+		d.pass("%s contains %d themes", dir, len(themes))
+		return
+	}
+
+	d.warn("Fix or remove them, or hide them with .alacrithemeignore.", "%d of %d themes in %s are not valid TOML:\n      %s",
+		len(invalid), len(themes), dir, strings.Join(invalid, "\n      "))
+}
+
+// checkConfig checks that the Alacritty config parses and can be rewritten,
+// and returns it parsed
+func (d *doctor) checkConfig(configFile string) map[string]interface{} {
+	if configFile == "" {
+		d.fail("Pass --config or set CONFIG_FILE.", "no Alacritty config found")
+		return nil
+	}
+	if legacy, ok := legacyConfig(configFile); ok {
+		d.fail("Run alacritheme migrate to convert it to TOML.", "%s is a YAML config, which alacritheme can't edit", legacy)
+		return nil
+	}
+
+	var config map[string]interface{}
+	content, err := os.ReadFile(configFile)
+	switch {
+	case os.IsNotExist(err):
+		d.pass("%s doesn't exist yet and will be created", configFile)
+	case err != nil:
+		d.fail("Check the file's permissions.", "can't read %s: %v", configFile, err)
+	default:
+		if err := toml.Unmarshal(content, &config); err != nil {
+			d.fail("Fix the syntax error, alacritheme can't update a config it can't parse.", "%s is not valid TOML: %v", configFile, err)
+		} else {
+			d.pass("%s is valid", configFile)
+		}
+	}
+
+	// writeConfig replaces the file through a temporary file next to it
+	dir := filepath.Dir(configFile)
+	if resolved, err := filepath.EvalSymlinks(configFile); err == nil {
+		dir = filepath.Dir(resolved)
+	}
+	d.checkWritable(dir, "config directory")
+
+	return config
+}
+
+// checkWritable checks that files can be created in dir, creating it if
+// needed
+func (d *doctor) checkWritable(dir, what string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		d.fail("Check the permissions of its parent directories.", "can't create the %s %s: %v", what, dir, err)
+		return
+	}
+
+	file, err := os.CreateTemp(dir, ".alacritheme-doctor-*")
+	if err != nil {
+		d.fail("Make it writable, e.g. chmod u+w "+dir, "the %s %s is not writable", what, dir)
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	d.pass("the %s %s is writable", what, dir)
+}
+
+// checkAlacritty checks that the installed Alacritty reads TOML configs and
+// the import key where alacritheme writes it
+func (d *doctor) checkAlacritty(config map[string]interface{}) {
+	version, err := installedAlacritty()
+	if err != nil {
+		d.warn("Install Alacritty, or make sure it's in PATH, to check its version.", "alacritty not found in PATH")
+		return
+	}
+
+	major, minor, ok := parseAlacrittyVersion(version)
+	if !ok {
+		d.warn("Make sure Alacritty is 0.14 or newer.", "can't tell the version of %q", version)
+		return
+	}
+
+	_, general := config["general"].(map[string]interface{})
+	_, topLevel := config["import"]
+	switch {
+	case major == 0 && minor < 13:
+		d.fail("Upgrade Alacritty to 0.14 or newer, older versions don't read TOML configs.", "%s only reads YAML configs", version)
+	case major == 0 && minor < 14 && general:
+		d.fail("Upgrade Alacritty to 0.14 or newer, or move the settings below [general] to the top level.", "%s doesn't know [general], where alacritheme writes the import", version)
+	case major == 0 && minor < 14:
+		d.pass("%s reads the top-level import", version)
+	case topLevel:
+		d.warn("Apply a theme to move import and live_config_reload below [general], Alacritty 0.14 deprecated them at the top level.", "%s warns about the top-level import", version)
+	default:
+		d.pass("%s supports [general].import", version)
+	}
+}
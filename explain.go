@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// runExplain describes what a command would do without doing it, e.g.
+// "explain apply dracula"
+func runExplain(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("explain", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 || args[0] != "apply" {
+		return usage("explain")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	themesDirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	theme, err := resolveTheme(themesDirs, args[1])
+	if err != nil {
+		return err
+	}
+	trace("resolved theme %s to %s", args[1], theme)
+
+	if err := checkLegacyConfig(configFile); err != nil {
+		return err
+	}
+	trace("reading %s", configFile)
+	content, err := os.ReadFile(configFile)
+	missing := os.IsNotExist(err)
+	if err != nil && !missing {
+		return err
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	updated, err := applyTheme(content, configFile, theme, cfg.applyOptions())
+	if err != nil {
+		return err
+	}
+
+	changes, err := configChanges(content, updated)
+	if err != nil {
+		return err
+	}
+
+	var config map[string]interface{}
+	_ = toml.Unmarshal(content, &config)
+	previous := importedTheme(config, configFile)
+
+	info("Applying %s would modify %s.\n\n", filepath.Base(theme), configFile)
+	if missing {
+		info("The file doesn't exist yet and would be created.\n")
+	}
+
+	if len(changes) == 0 {
+		info("Nothing would change, the theme is already applied.\n")
+		return nil
+	}
+
+	info("Changed keys:\n")
+	for _, change := range changes {
+		info("  %s\n", change)
+	}
+
+	info("\nOnly these keys are edited, the rest of the file is left as it is.\n")
+	dir, err := backupsDir()
+	if err != nil {
+		return err
+	}
+	info("\nBackup:\n")
+	info("  alacritheme copies the original into\n")
+	info("  %s\n", dir)
+	info("  before it changes it. The TUI writes it back when you quit with q or\n")
+	info("  ctrl+c, pressing enter keeps the new theme.\n")
+	info("\nRevert:\n")
+	info("  alacritheme rollback\n")
+	if previous != "" {
+		info("  or select %s in alacritheme and press enter, or set the import back to\n", filepath.Base(previous))
+		info("  %q in %s.\n", previous, configFile)
+	} else {
+		info("  or remove the import of %q from %s.\n", theme, configFile)
+	}
+
+	info("\n")
+	result("%s", unifiedDiff(configFile, configFile, string(content), string(updated)))
+	return nil
+}
+
+// configChanges lists the keys that differ between two configs in a
+// "key: old → new" form
+func configChanges(before, after []byte) ([]string, error) {
+	var a, b map[string]interface{}
+	if err := toml.Unmarshal(before, &a); err != nil {
+		return nil, err
+	}
+	if err := toml.Unmarshal(after, &b); err != nil {
+		return nil, err
+	}
+
+	flatA, flatB := make(map[string]string), make(map[string]string)
+	flattenConfig(a, "", flatA)
+	flattenConfig(b, "", flatB)
+
+	keys := make(map[string]bool)
+	for k := range flatA {
+		keys[k] = true
+	}
+	for k := range flatB {
+		keys[k] = true
+	}
+
+	var changes []string
+	for k := range keys {
+		oldValue, hadOld := flatA[k]
+		newValue, hasNew := flatB[k]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("%s: (unset) → %s", k, newValue))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("%s: %s → (removed)", k, oldValue))
+		case oldValue != newValue:
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", k, oldValue, newValue))
+		}
+	}
+	sort.Strings(changes)
+
+	return changes, nil
+}
+
+// flattenConfig collects the leaf values of a decoded config under their
+// dotted keys
+func flattenConfig(config map[string]interface{}, prefix string, out map[string]string) {
+	for k, v := range config {
+		if table, ok := v.(map[string]interface{}); ok {
+			flattenConfig(table, prefix+k+".", out)
+			continue
+		}
+
+		value, err := toml.Marshal(map[string]interface{}{"v": v})
+		if err != nil {
+			out[prefix+k] = fmt.Sprint(v)
+			continue
+		}
+		out[prefix+k] = strings.TrimSpace(strings.TrimPrefix(string(value), "v = "))
+	}
+}
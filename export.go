@@ -0,0 +1,711 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exportFormat converts a theme into another terminal's color format
+type exportFormat struct {
+	ext string
+	// dir is where the terminal looks for themes, relative to the user's
+	// config directory
+	dir   string
+	write func(name string, scheme ColorScheme) ([]byte, error)
+}
+
+// exportFormats are the supported targets, keyed by the name given to --to
+var exportFormats = map[string]exportFormat{
+	"base16":           {ext: ".yaml", write: exportBase16},
+	"foot":             {ext: ".ini", write: exportFoot},
+	"fzf":              {ext: ".fzf", write: exportFzf},
+	"ghostty":          {dir: "ghostty/themes", write: exportGhostty},
+	"hyper":            {ext: ".js", write: exportHyper},
+	"kitty":            {ext: ".conf", write: exportKitty},
+	"konsole":          {ext: ".colorscheme", write: exportKonsole},
+	"termux":           {ext: ".properties", write: exportTermux},
+	"tmux":             {ext: ".tmux.conf", write: exportTmux},
+	"vscode":           {ext: ".json", write: exportVSCode},
+	"wezterm":          {ext: ".toml", dir: "wezterm/colors", write: exportWezterm},
+	"zellij":           {ext: ".kdl", dir: "zellij/themes", write: exportZellij},
+	"wezterm-lua":      {ext: ".lua", dir: "wezterm/colors", write: exportWeztermLua},
+	"windows-terminal": {ext: ".json", write: exportWindowsTerminal},
+	"xresources":       {ext: ".Xresources", write: exportXresources},
+}
+
+// runExport converts a single theme to another terminal's format and prints
+// it, or writes it to a file: export <theme> --to <format> [--out file]
+// [--set-colors]
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	to := flags.String("to", "", "target format ("+strings.Join(exportFormatNames(), ", ")+")")
+	out := flags.String("out", "", "file the converted theme is written to instead of stdout")
+	setColors := flags.Bool("set-colors", false, "also apply the colors to the running kitty, with --to kitty")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *to == "" || (*setColors && *to != "kitty") {
+		return usage("export")
+	}
+
+	format, err := lookupFormat(*to)
+	if err != nil {
+		return err
+	}
+
+	themesDirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	path, err := resolveTheme(themesDirs, positional[0])
+	if err != nil {
+		return err
+	}
+	trace("resolved theme %s to %s", positional[0], path)
+
+	scheme, err := readScheme(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := format.write(schemeName(path), scheme)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	if *setColors {
+		if err := kittySetColors(content, true); err != nil {
+			return err
+		}
+		info("Applied %s to kitty\n", filepath.Base(path))
+		if *out == "" {
+			return nil
+		}
+	}
+
+	if *out == "" {
+		result("%s", content)
+		return nil
+	}
+
+	trace("writing %s", *out)
+	if err := os.WriteFile(*out, content, 0644); err != nil {
+		return err
+	}
+	info("Exported %s to %s\n", filepath.Base(path), *out)
+	return nil
+}
+
+// schemeName returns the name a theme gives itself in a "# Colors (Name)"
+// header, as the official themes and imported schemes have, or its file name
+func schemeName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".toml")
+	if source := convertedSource(path); source != "" {
+		name = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return name
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if header, ok := strings.CutPrefix(line, "# Colors ("); ok && strings.HasSuffix(header, ")") {
+			return strings.TrimSuffix(header, ")")
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+	}
+
+	return name
+}
+
+// exportFormatNames returns the names of the supported formats in a stable
+// order, for usage messages
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	if scripts, err := loadedScripts(); err == nil && scripts != nil {
+		for name := range scripts.exporters {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// lookupFormat returns the export format called name
+func lookupFormat(name string) (exportFormat, error) {
+	format, ok := exportFormats[name]
+	if !ok {
+		scripts, err := loadedScripts()
+		if err != nil {
+			return exportFormat{}, err
+		}
+		format, ok = scripts.exporter(name)
+	}
+	if !ok {
+		return exportFormat{}, fmt.Errorf("unknown format %q, supported formats are %s", name, strings.Join(exportFormatNames(), ", "))
+	}
+
+	return format, nil
+}
+
+// schemeColors returns the primary and palette colors of the scheme as
+// #rrggbb keyed by slot, failing if any of them is missing or invalid, since
+// other terminals expect a complete palette
+func schemeColors(scheme ColorScheme) (map[string]string, error) {
+	colors := make(map[string]string)
+	for _, slot := range scheme.slots() {
+		if slot.Color == "" {
+			return nil, fmt.Errorf("missing color %s", slot.Key)
+		}
+		c, err := parseColor(slot.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %s = %q", slot.Key, slot.Color)
+		}
+		colors[slot.Key] = c.Hex()
+	}
+
+	return colors, nil
+}
+
+// ansiKeys are the slots of the 16 ANSI colors, in color0 to color15 order
+var ansiKeys = []string{
+	"normal.black", "normal.red", "normal.green", "normal.yellow",
+	"normal.blue", "normal.magenta", "normal.cyan", "normal.white",
+	"bright.black", "bright.red", "bright.green", "bright.yellow",
+	"bright.blue", "bright.magenta", "bright.cyan", "bright.white",
+}
+
+// hyperColorNames are Hyper's names of the 16 ANSI colors, in color0 to
+// color15 order
+var hyperColorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"lightBlack", "lightRed", "lightGreen", "lightYellow", "lightBlue", "lightMagenta", "lightCyan", "lightWhite",
+}
+
+func exportHyper(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s, exported by alacritheme\n", name)
+	fmt.Fprintf(&b, "// Merge config into the one of ~/.hyper.js\n")
+	fmt.Fprintf(&b, "module.exports = {\n")
+	fmt.Fprintf(&b, "  config: {\n")
+	fmt.Fprintf(&b, "    backgroundColor: %q,\n", colors["primary.background"])
+	fmt.Fprintf(&b, "    foregroundColor: %q,\n", colors["primary.foreground"])
+	c := scheme.Colors
+	for _, option := range []struct{ key, value string }{
+		{"cursorColor", c.Cursor.Cursor},
+		{"cursorAccentColor", c.Cursor.Text},
+		{"selectionColor", c.Selection.Background},
+	} {
+		if color := optionalColor(option.value); color != "" {
+			fmt.Fprintf(&b, "    %s: %q,\n", option.key, color)
+		}
+	}
+	fmt.Fprintf(&b, "    colors: {\n")
+	for i, key := range ansiKeys {
+		fmt.Fprintf(&b, "      %s: %q,\n", hyperColorNames[i], colors[key])
+	}
+	fmt.Fprintf(&b, "    },\n")
+	fmt.Fprintf(&b, "  },\n")
+	fmt.Fprintf(&b, "};\n")
+
+	return []byte(b.String()), nil
+}
+
+func exportKitty(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "foreground %s\n", colors["primary.foreground"])
+	fmt.Fprintf(&b, "background %s\n\n", colors["primary.background"])
+	for i, key := range ansiKeys {
+		fmt.Fprintf(&b, "color%d %s\n", i, colors[key])
+	}
+
+	return []byte(b.String()), nil
+}
+
+// blendSlots returns the color t of the way from one slot's color to
+// another's, for shades and accents terminals don't have a slot for
+func blendSlots(colors map[string]string, from, to string, t float64) string {
+	a, _ := parseColor(colors[from])
+	b, _ := parseColor(colors[to])
+
+	return a.BlendLab(b, t).Clamped().Hex()
+}
+
+// surfaceColor returns a shade a tenth of the way from the background to the
+// foreground, for bars and highlighted lines that stand apart from the text
+func surfaceColor(colors map[string]string) string {
+	return blendSlots(colors, "primary.background", "primary.foreground", 0.1)
+}
+
+func exportBase16(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	blend := func(from, to string, t float64) string {
+		return blendSlots(colors, from, to, t)
+	}
+	// The terminal colors base16-shell reads are taken as they are, the
+	// shades and accents terminals lack are blended from them
+	bases := []string{
+		colors["primary.background"],
+		surfaceColor(colors),
+		blend("primary.background", "primary.foreground", 0.2),
+		colors["bright.black"],
+		blend("bright.black", "primary.foreground", 0.5),
+		colors["primary.foreground"],
+		blend("primary.foreground", "bright.white", 0.5),
+		colors["bright.white"],
+		colors["normal.red"],
+		blend("normal.red", "normal.yellow", 0.5),
+		colors["normal.yellow"],
+		colors["normal.green"],
+		colors["normal.cyan"],
+		colors["normal.blue"],
+		colors["normal.magenta"],
+		blend("normal.red", "primary.background", 0.4),
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "scheme: %q\n", name)
+	fmt.Fprintf(&b, "author: \"exported by alacritheme\"\n")
+	for i, color := range bases {
+		fmt.Fprintf(&b, "base%02X: \"%s\"\n", i, strings.TrimPrefix(color, "#"))
+	}
+
+	return []byte(b.String()), nil
+}
+
+func exportFzf(_ string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []string{
+		"bg:" + colors["primary.background"],
+		"fg:" + colors["primary.foreground"],
+		"hl:" + colors["normal.blue"],
+		"bg+:" + surfaceColor(colors),
+		"fg+:" + colors["bright.white"],
+		"hl+:" + colors["bright.blue"],
+		"info:" + colors["normal.yellow"],
+		"prompt:" + colors["normal.green"],
+		"pointer:" + colors["normal.red"],
+		"marker:" + colors["normal.green"],
+		"spinner:" + colors["normal.magenta"],
+		"header:" + colors["normal.cyan"],
+		"border:" + colors["bright.black"],
+	}
+
+	// No header comment, the line goes straight into FZF_DEFAULT_OPTS
+	return []byte("--color=" + strings.Join(options, ",") + "\n"), nil
+}
+
+func exportKonsole(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// Konsole writes colors as r,g,b
+	rgb := func(key string) string {
+		c, _ := parseColor(colors[key])
+		r, g, b := c.RGB255()
+		return fmt.Sprintf("%d,%d,%d", r, g, b)
+	}
+
+	var b strings.Builder
+	section := func(name, key string) {
+		fmt.Fprintf(&b, "[%s]\nColor=%s\n\n", name, rgb(key))
+	}
+	section("Background", "primary.background")
+	section("BackgroundIntense", "primary.background")
+	for i, key := range ansiKeys[:8] {
+		section(fmt.Sprintf("Color%d", i), key)
+		section(fmt.Sprintf("Color%dIntense", i), ansiKeys[i+8])
+	}
+	section("Foreground", "primary.foreground")
+	section("ForegroundIntense", "bright.white")
+	fmt.Fprintf(&b, "[General]\nDescription=%s\nOpacity=1\n", name)
+
+	return []byte(b.String()), nil
+}
+
+func exportTermux(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "background=%s\n", colors["primary.background"])
+	fmt.Fprintf(&b, "foreground=%s\n", colors["primary.foreground"])
+	if cursor := optionalColor(scheme.Colors.Cursor.Cursor); cursor != "" {
+		fmt.Fprintf(&b, "cursor=%s\n", cursor)
+	}
+	b.WriteString("\n")
+	for i, key := range ansiKeys {
+		fmt.Fprintf(&b, "color%d=%s\n", i, colors[key])
+	}
+
+	return []byte(b.String()), nil
+}
+
+func exportTmux(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// The status line sits a little apart from the panes
+	surface := surfaceColor(colors)
+	accent := colors["normal.blue"]
+	muted := colors["bright.black"]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n", name)
+	fmt.Fprintf(&b, "# Load it with source-file in .tmux.conf\n\n")
+	fmt.Fprintf(&b, "set -g status-style \"bg=%s,fg=%s\"\n", surface, colors["primary.foreground"])
+	fmt.Fprintf(&b, "set -g window-status-style \"bg=%s,fg=%s\"\n", surface, muted)
+	fmt.Fprintf(&b, "set -g window-status-current-style \"bg=%s,fg=%s,bold\"\n", accent, colors["primary.background"])
+	fmt.Fprintf(&b, "set -g window-status-activity-style \"bg=%s,fg=%s\"\n", surface, colors["normal.yellow"])
+	fmt.Fprintf(&b, "set -g pane-border-style \"fg=%s\"\n", muted)
+	fmt.Fprintf(&b, "set -g pane-active-border-style \"fg=%s\"\n", accent)
+	fmt.Fprintf(&b, "set -g message-style \"bg=%s,fg=%s\"\n", colors["normal.yellow"], colors["primary.background"])
+	fmt.Fprintf(&b, "set -g message-command-style \"bg=%s,fg=%s\"\n", colors["normal.magenta"], colors["primary.background"])
+	fmt.Fprintf(&b, "set -g mode-style \"bg=%s,fg=%s\"\n", accent, colors["primary.background"])
+	fmt.Fprintf(&b, "set -g display-panes-active-colour \"%s\"\n", accent)
+	fmt.Fprintf(&b, "set -g display-panes-colour \"%s\"\n", muted)
+	fmt.Fprintf(&b, "set -g clock-mode-colour \"%s\"\n", accent)
+
+	return []byte(b.String()), nil
+}
+
+// vscodeANSINames are VS Code's names of the 16 ANSI colors, in color0 to
+// color15 order
+var vscodeANSINames = []string{
+	"Black", "Red", "Green", "Yellow", "Blue", "Magenta", "Cyan", "White",
+	"BrightBlack", "BrightRed", "BrightGreen", "BrightYellow", "BrightBlue", "BrightMagenta", "BrightCyan", "BrightWhite",
+}
+
+func exportVSCode(_ string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	terminal := map[string]string{
+		"terminal.background": colors["primary.background"],
+		"terminal.foreground": colors["primary.foreground"],
+	}
+	for i, key := range ansiKeys {
+		terminal["terminal.ansi"+vscodeANSINames[i]] = colors[key]
+	}
+	c := scheme.Colors
+	for key, value := range map[string]string{
+		"terminalCursor.foreground":    c.Cursor.Cursor,
+		"terminalCursor.background":    c.Cursor.Text,
+		"terminal.selectionBackground": c.Selection.Background,
+		"terminal.selectionForeground": c.Selection.Text,
+	} {
+		if color := optionalColor(value); color != "" {
+			terminal[key] = color
+		}
+	}
+
+	// A block to merge into settings.json, which has no room for the name
+	content, err := json.MarshalIndent(map[string]map[string]string{"workbench.colorCustomizations": terminal}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+func exportWezterm(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := make([]string, len(ansiKeys))
+	for i, key := range ansiKeys {
+		quoted[i] = fmt.Sprintf("%q", colors[key])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "[colors]\n")
+	fmt.Fprintf(&b, "foreground = %q\n", colors["primary.foreground"])
+	fmt.Fprintf(&b, "background = %q\n", colors["primary.background"])
+	fmt.Fprintf(&b, "ansi = [%s]\n", strings.Join(quoted[:8], ", "))
+	fmt.Fprintf(&b, "brights = [%s]\n\n", strings.Join(quoted[8:], ", "))
+	fmt.Fprintf(&b, "[metadata]\n")
+	fmt.Fprintf(&b, "name = %q\n", name)
+
+	return []byte(b.String()), nil
+}
+
+func exportZellij(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// bg is the background of Zellij's own frames and tabs, not the panes'
+	values := []struct{ key, color string }{
+		{"fg", colors["primary.foreground"]},
+		{"bg", surfaceColor(colors)},
+		{"black", colors["normal.black"]},
+		{"red", colors["normal.red"]},
+		{"green", colors["normal.green"]},
+		{"yellow", colors["normal.yellow"]},
+		{"blue", colors["normal.blue"]},
+		{"magenta", colors["normal.magenta"]},
+		{"cyan", colors["normal.cyan"]},
+		{"white", colors["normal.white"]},
+		{"orange", blendSlots(colors, "normal.red", "normal.yellow", 0.5)},
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "themes {\n")
+	fmt.Fprintf(&b, "    %q {\n", name)
+	for _, value := range values {
+		fmt.Fprintf(&b, "        %s %q\n", value.key, value.color)
+	}
+	fmt.Fprintf(&b, "    }\n")
+	fmt.Fprintf(&b, "}\n")
+
+	return []byte(b.String()), nil
+}
+
+// windowsTerminalScheme is an entry of the schemes array in Windows
+// Terminal's settings.json, in the order Windows Terminal writes it
+type windowsTerminalScheme struct {
+	Name         string `json:"name"`
+	Background   string `json:"background"`
+	Foreground   string `json:"foreground"`
+	Black        string `json:"black"`
+	Red          string `json:"red"`
+	Green        string `json:"green"`
+	Yellow       string `json:"yellow"`
+	Blue         string `json:"blue"`
+	Purple       string `json:"purple"`
+	Cyan         string `json:"cyan"`
+	White        string `json:"white"`
+	BrightBlack  string `json:"brightBlack"`
+	BrightRed    string `json:"brightRed"`
+	BrightGreen  string `json:"brightGreen"`
+	BrightYellow string `json:"brightYellow"`
+	BrightBlue   string `json:"brightBlue"`
+	BrightPurple string `json:"brightPurple"`
+	BrightCyan   string `json:"brightCyan"`
+	BrightWhite  string `json:"brightWhite"`
+	// Optional, left out when the theme has none
+	CursorColor         string `json:"cursorColor,omitempty"`
+	SelectionBackground string `json:"selectionBackground,omitempty"`
+}
+
+func exportWeztermLua(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := make([]string, len(ansiKeys))
+	for i, key := range ansiKeys {
+		quoted[i] = fmt.Sprintf("%q", colors[key])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "return {\n")
+	fmt.Fprintf(&b, "  foreground = %q,\n", colors["primary.foreground"])
+	fmt.Fprintf(&b, "  background = %q,\n", colors["primary.background"])
+	fmt.Fprintf(&b, "  ansi = { %s },\n", strings.Join(quoted[:8], ", "))
+	fmt.Fprintf(&b, "  brights = { %s },\n", strings.Join(quoted[8:], ", "))
+	fmt.Fprintf(&b, "}\n")
+
+	return []byte(b.String()), nil
+}
+
+// exportDir returns where the TUI exports themes in format: the configured
+// directory, or the one the terminal looks for themes in
+func exportDir(cfg exportConfig, name string, format exportFormat) (string, error) {
+	if cfg.Dir != "" {
+		return cfg.Dir, nil
+	}
+	if format.dir == "" {
+		return "", fmt.Errorf("export.dir isn't set, it's needed to export to %s", name)
+	}
+
+	config, err := configHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(config, filepath.FromSlash(format.dir)), nil
+}
+
+// optionalColor returns the color as #rrggbb, or an empty string for colors
+// that aren't set or name a cell color, which other terminals lack
+func optionalColor(value string) string {
+	c, err := parseColor(value)
+	if value == "" || err != nil {
+		return ""
+	}
+
+	return c.Hex()
+}
+
+func exportFoot(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	// foot writes colors as rrggbb, without the #
+	hex := func(color string) string { return strings.TrimPrefix(color, "#") }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "[colors]\n")
+	fmt.Fprintf(&b, "background=%s\n", hex(colors["primary.background"]))
+	fmt.Fprintf(&b, "foreground=%s\n", hex(colors["primary.foreground"]))
+	for i, key := range ansiKeys[:8] {
+		fmt.Fprintf(&b, "regular%d=%s\n", i, hex(colors[key]))
+	}
+	for i, key := range ansiKeys[8:] {
+		fmt.Fprintf(&b, "bright%d=%s\n", i, hex(colors[key]))
+	}
+
+	c := scheme.Colors
+	// foot's cursor takes both colors, or none
+	text, cursor := optionalColor(c.Cursor.Text), optionalColor(c.Cursor.Cursor)
+	if text != "" && cursor != "" {
+		fmt.Fprintf(&b, "cursor=%s %s\n", hex(text), hex(cursor))
+	}
+	if color := optionalColor(c.Selection.Text); color != "" {
+		fmt.Fprintf(&b, "selection-foreground=%s\n", hex(color))
+	}
+	if color := optionalColor(c.Selection.Background); color != "" {
+		fmt.Fprintf(&b, "selection-background=%s\n", hex(color))
+	}
+
+	return []byte(b.String()), nil
+}
+
+func exportGhostty(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "background = %s\n", colors["primary.background"])
+	fmt.Fprintf(&b, "foreground = %s\n", colors["primary.foreground"])
+	c := scheme.Colors
+	for _, option := range []struct{ key, value string }{
+		{"cursor-color", c.Cursor.Cursor},
+		{"cursor-text", c.Cursor.Text},
+		{"selection-background", c.Selection.Background},
+		{"selection-foreground", c.Selection.Text},
+	} {
+		if color := optionalColor(option.value); color != "" {
+			fmt.Fprintf(&b, "%s = %s\n", option.key, color)
+		}
+	}
+	b.WriteString("\n")
+	for i, key := range ansiKeys {
+		fmt.Fprintf(&b, "palette = %d=%s\n", i, colors[key])
+	}
+
+	return []byte(b.String()), nil
+}
+
+func exportWindowsTerminal(name string, scheme ColorScheme) ([]byte, error) {
+	c, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.MarshalIndent(windowsTerminalScheme{
+		Name:         name,
+		Background:   c["primary.background"],
+		Foreground:   c["primary.foreground"],
+		Black:        c["normal.black"],
+		Red:          c["normal.red"],
+		Green:        c["normal.green"],
+		Yellow:       c["normal.yellow"],
+		Blue:         c["normal.blue"],
+		Purple:       c["normal.magenta"],
+		Cyan:         c["normal.cyan"],
+		White:        c["normal.white"],
+		BrightBlack:  c["bright.black"],
+		BrightRed:    c["bright.red"],
+		BrightGreen:  c["bright.green"],
+		BrightYellow: c["bright.yellow"],
+		BrightBlue:   c["bright.blue"],
+		BrightPurple: c["bright.magenta"],
+		BrightCyan:   c["bright.cyan"],
+		BrightWhite:  c["bright.white"],
+		// Cell colors such as "CellForeground" are left to Windows
+		// Terminal's defaults
+		CursorColor:         optionalColor(scheme.Colors.Cursor.Cursor),
+		SelectionBackground: optionalColor(scheme.Colors.Selection.Background),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+func exportXresources(name string, scheme ColorScheme) ([]byte, error) {
+	colors, err := schemeColors(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "! %s, exported by alacritheme\n\n", name)
+	fmt.Fprintf(&b, "*.foreground: %s\n", colors["primary.foreground"])
+	fmt.Fprintf(&b, "*.background: %s\n\n", colors["primary.background"])
+	for i, key := range ansiKeys {
+		fmt.Fprintf(&b, "*.color%d: %s\n", i, colors[key])
+	}
+
+	return []byte(b.String()), nil
+}
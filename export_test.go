@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// testScheme is a complete scheme with a different color in every slot
+const testScheme = `
+[colors.primary]
+background = '#101010'
+foreground = '#e0e0e0'
+
+[colors.normal]
+black = '#000001'
+red = '#aa0000'
+green = '#00aa00'
+yellow = '#aaaa00'
+blue = '#0000aa'
+magenta = '#aa00aa'
+cyan = '#00aaaa'
+white = '#aaaaaa'
+
+[colors.bright]
+black = '#555555'
+red = '#ff5555'
+green = '#55ff55'
+yellow = '#ffff55'
+blue = '#5555ff'
+magenta = '#ff55ff'
+cyan = '#55ffff'
+white = '#ffffff'
+`
+
+func parseTestScheme(t *testing.T) ColorScheme {
+	t.Helper()
+	var scheme ColorScheme
+	if err := toml.Unmarshal([]byte(testScheme), &scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"base16", []string{`scheme: "Test"`, `base00: "101010"`, `base05: "e0e0e0"`, `base07: "ffffff"`, `base08: "aa0000"`, `base0D: "0000aa"`}},
+		{"foot", []string{"[colors]", "background=101010", "foreground=e0e0e0", "regular0=000001", "regular1=aa0000", "bright7=ffffff"}},
+		{"fzf", []string{"--color=bg:#101010,fg:#e0e0e0,hl:#0000aa,", "pointer:#aa0000"}},
+		{"ghostty", []string{"background = #101010", "foreground = #e0e0e0", "palette = 0=#000001", "palette = 1=#aa0000", "palette = 15=#ffffff"}},
+		{"hyper", []string{`backgroundColor: "#101010"`, `foregroundColor: "#e0e0e0"`, `black: "#000001"`, `red: "#aa0000"`, `lightWhite: "#ffffff"`}},
+		{"kitty", []string{"background #101010", "foreground #e0e0e0", "color0 #000001", "color1 #aa0000", "color15 #ffffff"}},
+		{"konsole", []string{"[Background]\nColor=16,16,16", "[Color0]\nColor=0,0,1", "[Color1]\nColor=170,0,0", "[Color7Intense]\nColor=255,255,255", "Description=Test"}},
+		{"termux", []string{"background=#101010", "foreground=#e0e0e0", "color0=#000001", "color1=#aa0000", "color15=#ffffff"}},
+		{"tmux", []string{`set -g status-style "bg=#222222,fg=#e0e0e0"`, `set -g window-status-current-style "bg=#0000aa,fg=#101010,bold"`}},
+		{"vscode", []string{`"terminal.background": "#101010"`, `"terminal.foreground": "#e0e0e0"`, `"terminal.ansiBlack": "#000001"`, `"terminal.ansiRed": "#aa0000"`, `"terminal.ansiBrightWhite": "#ffffff"`}},
+		{"wezterm", []string{`background = "#101010"`, `foreground = "#e0e0e0"`, `ansi = ["#000001", "#aa0000",`, `"#55ffff", "#ffffff"]`, `name = "Test"`}},
+		{"wezterm-lua", []string{`background = "#101010"`, `foreground = "#e0e0e0"`, `ansi = { "#000001", "#aa0000",`, `"#55ffff", "#ffffff" }`}},
+		{"windows-terminal", []string{`"name": "Test"`, `"background": "#101010"`, `"black": "#000001"`, `"purple": "#aa00aa"`, `"brightWhite": "#ffffff"`}},
+		{"xresources", []string{"*.background: #101010", "*.foreground: #e0e0e0", "*.color0: #000001", "*.color1: #aa0000", "*.color15: #ffffff"}},
+		{"zellij", []string{`"Test" {`, `fg "#e0e0e0"`, `black "#000001"`, `red "#aa0000"`, `white "#aaaaaa"`}},
+	}
+	if len(tests) != len(exportFormats) {
+		t.Errorf("%d of %d formats tested", len(tests), len(exportFormats))
+	}
+
+	scheme := parseTestScheme(t)
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := exportFormats[tt.format].write("Test", scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("missing %q in\n%s", want, out)
+				}
+			}
+
+			incomplete := scheme
+			incomplete.Colors.Bright.White = ""
+			if _, err := exportFormats[tt.format].write("Test", incomplete); err == nil || !strings.Contains(err.Error(), "bright.white") {
+				t.Errorf("got error %v for a scheme without bright.white", err)
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	scheme := parseTestScheme(t)
+	want, err := schemeColors(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"kitty", "windows-terminal", "xresources"} {
+		t.Run(format, func(t *testing.T) {
+			out, err := exportFormats[format].write("Test", scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			importer := importFormats[format]
+			var got map[string]string
+			if importer.parseAll != nil {
+				schemes, err := importer.parseAll(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(schemes) != 1 {
+					t.Fatalf("got %d schemes, want 1", len(schemes))
+				}
+				got = schemes[0].colors
+			} else if got, err = importer.parse(out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// exportJob is a theme to export, and where below the output directory
+type exportJob struct {
+	path string
+	rel  string
+}
+
+// exportResult is the outcome of exporting a single theme
+type exportResult struct {
+	rel string
+	err error
+}
+
+// runExportAll converts every theme in the themes directories to another
+// format: export-all --to <format> --out <dir> [--jobs n]
+func runExportAll(args []string) error {
+	flags := flag.NewFlagSet("export-all", flag.ContinueOnError)
+	to := flags.String("to", "", "target format ("+strings.Join(exportFormatNames(), ", ")+")")
+	out := flags.String("out", "", "directory the converted themes are written to")
+	jobs := flags.Int("jobs", runtime.NumCPU(), "number of themes converted in parallel")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *to == "" || *out == "" {
+		return usage("export-all")
+	}
+
+	format, err := lookupFormat(*to)
+	if err != nil {
+		return err
+	}
+
+	themesDirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	// With several themes directories, each one gets its own subdirectory
+	// so that themes with the same name don't overwrite each other
+	var themes []exportJob
+	labels := sourceLabels(themesDirs)
+	for i, dir := range themesDirs {
+		rels, err := collectThemes(dir)
+		if err != nil {
+			return err
+		}
+		for _, rel := range rels {
+			job := exportJob{path: filepath.Join(dir, rel), rel: rel}
+			if len(themesDirs) > 1 {
+				job.rel = filepath.Join(labels[i], rel)
+			}
+			themes = append(themes, job)
+		}
+	}
+
+	queue := make(chan exportJob)
+	results := make(chan exportResult)
+	var wg sync.WaitGroup
+	for range max(*jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				results <- exportResult{rel: job.rel, err: exportTheme(job.path, job.rel, *out, format)}
+			}
+		}()
+	}
+	go func() {
+		for _, job := range themes {
+			queue <- job
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	var failures []exportResult
+	for res := range results {
+		if res.err != nil {
+			failures = append(failures, res)
+			continue
+		}
+		trace("exported %s", res.rel)
+	}
+
+	info("Exported %d of %d themes to %s\n", len(themes)-len(failures), len(themes), *out)
+	if len(failures) == 0 {
+		return nil
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].rel < failures[j].rel })
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d themes could not be exported:", len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(&b, "\n  %s: %v", f.rel, f.err)
+	}
+
+	return fmt.Errorf("%s", b.String())
+}
+
+// collectThemes returns the paths, relative to themesDir, of every theme the
+// TUI would list, skipping ignored entries and templates
+func collectThemes(themesDir string) ([]string, error) {
+	filter, err := newScanFilter(themesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var themes []string
+	err = filepath.WalkDir(themesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filter.ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".toml") || strings.HasSuffix(d.Name(), templateSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(themesDir, path)
+		if err != nil {
+			return err
+		}
+		themes = append(themes, rel)
+		return nil
+	})
+
+	return themes, err
+}
+
+// exportTheme converts the theme at path into rel below out
+func exportTheme(path, rel, out string, format exportFormat) error {
+	scheme, err := readScheme(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := format.write(schemeName(path), scheme)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(out, strings.TrimSuffix(rel, ".toml")+format.ext)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(target, content, 0644)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// officialThemesURL is a tarball of the alacritty/alacritty-theme repository
+const officialThemesURL = "https://github.com/alacritty/alacritty-theme/archive/refs/heads/master.tar.gz"
+
+// officialThemeURL is where a single theme of the official collection can be
+// downloaded, by file name
+const officialThemeURL = "https://raw.githubusercontent.com/alacritty/alacritty-theme/master/themes/"
+
+// officialRepoURL is the git repository of the official collection
+const officialRepoURL = "https://github.com/alacritty/alacritty-theme.git"
+
+// fetchStats counts what fetching a collection did with each of its themes.
+// added and removed are the file names of the themes that are new upstream
+// or no longer there.
+type fetchStats struct {
+	added, removed           []string
+	updated, unchanged, kept int
+}
+
+func (s fetchStats) String() string {
+	msg := fmt.Sprintf("%d new, %d updated, %d unchanged", len(s.added), s.updated, s.unchanged)
+	if len(s.removed) > 0 {
+		msg += fmt.Sprintf(", %d removed upstream", len(s.removed))
+	}
+	if s.kept > 0 {
+		msg += fmt.Sprintf(", %d with local changes kept", s.kept)
+	}
+
+	return msg
+}
+
+// merge adds the counts of other to s
+func (s *fetchStats) merge(other fetchStats) {
+	s.added = append(s.added, other.added...)
+	s.removed = append(s.removed, other.removed...)
+	s.updated += other.updated
+	s.unchanged += other.unchanged
+	s.kept += other.kept
+}
+
+// runFetch downloads the official alacritty-theme collection into the
+// themes directory, or updates the themes fetched before:
+// fetch [--git] [--dir dir] [--force]
+func runFetch(args []string) error {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	useGit := flags.Bool("git", false, "clone the repository with git instead of downloading an archive")
+	dir := flags.String("dir", "", "directory the themes are written to, the themes directory by default")
+	force := flags.Bool("force", false, "overwrite themes that were changed locally")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usage("fetch")
+	}
+
+	if *dir == "" {
+		if *dir, _, err = resolvePaths(); err != nil {
+			return err
+		}
+	}
+	if *dir == "" {
+		return fmt.Errorf("no themes directory, pass --dir or --themes-dir")
+	}
+	*dir = expandHome(*dir)
+
+	var themes map[string][]byte
+	var commit string
+	if *useGit {
+		themes, commit, err = officialCheckout()
+	} else {
+		themes, commit, err = officialArchiveWithProgress(newProgress("Downloading alacritty-theme"))
+	}
+	if err != nil {
+		return err
+	}
+
+	stats, err := writeOfficialThemes(*dir, themes, commit, *force)
+	if err != nil {
+		return err
+	}
+
+	info("%s: %s\n", *dir, stats)
+	if stats.kept > 0 {
+		info("Run alacritheme fetch --force to overwrite local changes, or edit themes in the TUI to keep them in the overlay.\n")
+	}
+	return nil
+}
+
+// fetchOfficialThemes downloads the official theme collection and writes its
+// themes into dir, returning how many were written
+func fetchOfficialThemes(dir string) (int, error) {
+	themes, commit, err := officialArchive()
+	if err != nil {
+		return 0, err
+	}
+
+	stats, err := writeOfficialThemes(dir, themes, commit, false)
+	return len(stats.added) + stats.updated, err
+}
+
+// writeOfficialThemes writes the themes of the official collection into dir,
+// skipping the ones that didn't change. Themes that were changed since they
+// were downloaded, or weren't downloaded by alacritheme, are kept unless
+// force is set.
+func writeOfficialThemes(dir string, themes map[string][]byte, commit string, force bool) (fetchStats, error) {
+	var stats fetchStats
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return stats, err
+	}
+
+	known, err := loadSources()
+	if err != nil {
+		return stats, err
+	}
+
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sources := make(map[string]themeSource)
+	for _, name := range names {
+		content := themes[name]
+		target := filepath.Join(dir, name)
+
+		existing, err := os.ReadFile(target)
+		switch {
+		case os.IsNotExist(err):
+			stats.added = append(stats.added, name)
+		case err != nil:
+			return stats, err
+		case bytes.Equal(existing, content):
+			stats.unchanged++
+		case force || isPristine(known, target, existing):
+			stats.updated++
+		default:
+			trace("keeping %s, it was changed locally", target)
+			stats.kept++
+			continue
+		}
+
+		if !bytes.Equal(existing, content) {
+			trace("writing %s", target)
+			if err := os.WriteFile(target, content, 0644); err != nil {
+				return stats, err
+			}
+		}
+		sources[target] = themeSource{
+			Origin: officialThemesURL,
+			URL:    officialThemeURL + name,
+			Commit: commit,
+			SHA256: contentHash(content),
+		}
+	}
+
+	return stats, recordSources(sources)
+}
+
+// isPristine reports whether the theme at path is still as it was downloaded
+func isPristine(known map[string]themeSource, path string, content []byte) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	source, ok := known[abs]
+	return ok && source.SHA256 == contentHash(content)
+}
+
+// officialCheckout clones the official collection with git, or pulls the
+// existing clone, and returns its themes by file name along with the commit
+func officialCheckout() (map[string][]byte, string, error) {
+	if err := requireOnline(officialRepoURL); err != nil {
+		return nil, "", err
+	}
+
+	state, err := appStateDir()
+	if err != nil {
+		return nil, "", err
+	}
+	repo := filepath.Join(state, "alacritty-theme")
+
+	if _, err := os.Stat(filepath.Join(repo, ".git")); err == nil {
+		err = runGit(repo, "pull", "--ff-only")
+	} else if err = os.MkdirAll(state, 0755); err == nil {
+		err = runGit(state, "clone", "--depth", "1", officialRepoURL, repo)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	files, err := filepath.Glob(filepath.Join(repo, "themes", "*.toml"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	themes := make(map[string][]byte)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, "", err
+		}
+		themes[filepath.Base(file)] = content
+	}
+
+	commit, _ := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	return themes, strings.TrimSpace(string(commit)), nil
+}
+
+// officialArchive downloads the official theme collection and returns its
+// themes by file name, along with the commit the archive was made from
+func officialArchive() (map[string][]byte, string, error) {
+	return officialArchiveWithProgress(nil)
+}
+
+// officialArchiveWithProgress is officialArchive, reporting the download on p
+func officialArchiveWithProgress(p *progress) (map[string][]byte, string, error) {
+	archive, err := fetchWithProgress(officialThemesURL, p)
+	if err != nil {
+		return nil, "", err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, "", err
+	}
+
+	themes := make(map[string][]byte)
+	var commit string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return themes, commit, nil
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		// GitHub stores the commit in the archive's global header
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			commit = hdr.PAXRecords["comment"]
+			continue
+		}
+
+		// Themes live in <repo>-<branch>/themes/*.toml
+		parts := strings.Split(hdr.Name, "/")
+		if hdr.Typeflag != tar.TypeReg || len(parts) != 3 || parts[1] != "themes" || path.Ext(hdr.Name) != ".toml" {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, "", err
+		}
+		themes[parts[2]] = content
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ghosttyInclude is the file in Ghostty's config directory the Ghostty
+// backend writes the selected theme to, included from Ghostty's config
+const ghosttyInclude = "alacritheme"
+
+// ghosttyIncludeLines are what includeGhosttyTheme adds to Ghostty's config
+const ghosttyIncludeLines = "\n# The theme applied by alacritheme\nconfig-file = ?" + ghosttyInclude + "\n"
+
+// ghosttyConfigDir returns Ghostty's config directory: the XDG one, or on
+// macOS the Application Support one when only that has a config
+func ghosttyConfigDir() (string, error) {
+	config, err := configHome()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(config, "ghostty")
+
+	if runtime.GOOS == "darwin" && !fileExists(ghosttyConfigFile(dir)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		support := filepath.Join(home, "Library", "Application Support", "com.mitchellh.ghostty")
+		if fileExists(ghosttyConfigFile(support)) {
+			return support, nil
+		}
+	}
+
+	return dir, nil
+}
+
+// ghosttyConfigFile returns Ghostty's config in dir, config or the newer
+// config.ghostty
+func ghosttyConfigFile(dir string) string {
+	path := filepath.Join(dir, "config")
+	if newer := filepath.Join(dir, "config.ghostty"); !fileExists(path) && fileExists(newer) {
+		return newer
+	}
+
+	return path
+}
+
+// ghosttyBackend writes the selected theme to ghosttyInclude and has the
+// running Ghostty reload its config with SIGUSR2
+type ghosttyBackend struct {
+	// original is the include before the first preview, nil if there was
+	// none, and saved tells whether it was read
+	original []byte
+	saved    bool
+	// included tells whether a preview added the include to Ghostty's
+	// config, which Restore takes out again
+	included bool
+}
+
+func (b *ghosttyBackend) Name() string { return "Ghostty" }
+
+// Detect reports whether alacritheme runs inside Ghostty
+func (b *ghosttyBackend) Detect() bool {
+	return os.Getenv("TERM_PROGRAM") == "ghostty" || os.Getenv("GHOSTTY_RESOURCES_DIR") != ""
+}
+
+func (b *ghosttyBackend) Preview(path string) error {
+	if dryRun {
+		return nil
+	}
+	scheme, err := readScheme(path)
+	if err != nil {
+		return err
+	}
+	theme, err := exportGhostty(schemeName(path), scheme)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ghosttyConfigDir()
+	if err != nil {
+		return err
+	}
+	include := filepath.Join(dir, ghosttyInclude)
+	if !b.saved {
+		b.original, err = os.ReadFile(include)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		b.saved = true
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	trace("writing %s", include)
+	if err := writeConfig(include, theme); err != nil {
+		return err
+	}
+	added, err := includeGhosttyTheme(dir)
+	if err != nil {
+		return err
+	}
+	b.included = b.included || added
+
+	return reloadGhostty()
+}
+
+// Apply is the same as a preview, the include is kept
+func (b *ghosttyBackend) Apply(path string) error {
+	if err := b.Preview(path); err != nil {
+		return err
+	}
+	b.included = false
+	return nil
+}
+
+// Restore brings back the include as it was and reloads Ghostty
+func (b *ghosttyBackend) Restore() error {
+	if dryRun || !b.saved {
+		return nil
+	}
+	dir, err := ghosttyConfigDir()
+	if err != nil {
+		return err
+	}
+	include := filepath.Join(dir, ghosttyInclude)
+
+	b.saved = false
+	if b.original == nil {
+		// The include is optional, Ghostty goes back to its own colors
+		trace("removing %s", include)
+		err = os.Remove(include)
+	} else {
+		err = writeConfig(include, b.original)
+	}
+	if err != nil {
+		return err
+	}
+	if b.included {
+		b.included = false
+		if err := removeGhosttyInclude(dir); err != nil {
+			return err
+		}
+	}
+
+	return reloadGhostty()
+}
+
+// includeGhosttyTheme adds an include of ghosttyInclude to the end of
+// Ghostty's config in dir, unless it has one, and reports whether it did.
+// The ? makes it optional, so Ghostty starts without it too.
+func includeGhosttyTheme(dir string) (bool, error) {
+	path := ghosttyConfigFile(dir)
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, "config-file") && strings.Contains(line, ghosttyInclude) {
+			return false, nil
+		}
+	}
+
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	content = append(content, ghosttyIncludeLines...)
+	trace("writing %s", path)
+	return true, writeConfig(path, content)
+}
+
+// removeGhosttyInclude takes the lines includeGhosttyTheme added out of
+// Ghostty's config in dir again, removing the config if it created it
+func removeGhosttyInclude(dir string) error {
+	path := ghosttyConfigFile(dir)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	content = bytes.Replace(content, []byte(ghosttyIncludeLines), nil, 1)
+	if len(content) == 0 {
+		trace("removing %s", path)
+		return os.Remove(path)
+	}
+	trace("writing %s", path)
+	return writeConfig(path, content)
+}
+
+// reloadGhostty has every running Ghostty reload its config. Only Ghostty on
+// Linux reloads on SIGUSR2, on macOS its reload_config keybinding
+// (cmd+shift+,) has to be pressed.
+func reloadGhostty() error {
+	if runtime.GOOS != "linux" {
+		trace("not reloading Ghostty, it only reloads on SIGUSR2 on Linux")
+		return nil
+	}
+
+	pkill, err := exec.LookPath("pkill")
+	if err != nil {
+		trace("not reloading Ghostty: %v", err)
+		return nil
+	}
+
+	trace("running pkill -USR2 -x ghostty")
+	// pkill fails when nothing matched, which is no error here
+	_ = exec.Command(pkill, "-USR2", "-x", "ghostty").Run()
+	return nil
+}
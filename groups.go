@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// loadGroups reads the named theme groups, each a list of theme paths
+func loadGroups() (map[string][]string, error) {
+	var file struct {
+		Groups map[string][]string `toml:"groups"`
+	}
+	err := loadState("groups.toml", &file)
+	if file.Groups == nil {
+		file.Groups = make(map[string][]string)
+	}
+
+	return file.Groups, err
+}
+
+// saveGroups writes the named theme groups back to disk
+func saveGroups(groups map[string][]string) error {
+	return saveState("groups.toml", map[string]interface{}{"groups": groups})
+}
+
+// groupNames returns the names of all groups in a stable order
+func groupNames(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// toggleMember adds path to the group, or removes it if it's already a member,
+// and reports whether path is a member afterwards. Empty groups are dropped.
+func toggleMember(groups map[string][]string, group, path string) bool {
+	members := groups[group]
+	for i, p := range members {
+		if p == path {
+			members = append(members[:i], members[i+1:]...)
+			if len(members) == 0 {
+				delete(groups, group)
+			} else {
+				groups[group] = members
+			}
+			return false
+		}
+	}
+
+	groups[group] = append(members, path)
+	return true
+}
+
+// groupItems builds list items for the members of a group, skipping themes
+// that no longer exist on disk
+func groupItems(members []string) []list.Item {
+	items := make([]list.Item, 0, len(members))
+	for _, path := range members {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		items = append(items, item{
+			title:   filepath.Base(path),
+			path:    path,
+			content: string(content),
+		})
+	}
+
+	return items
+}
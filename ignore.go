@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ignoreFileName is the gitignore-style file read from the root of the themes
+// directory
+const ignoreFileName = ".alacrithemeignore"
+
+// defaultIgnorePatterns hide dotfiles such as .git and .github, which
+// theme repositories are full of
+var defaultIgnorePatterns = []string{".*"}
+
+type ignorePattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// scanFilter decides which entries under the themes directory are listed
+type scanFilter struct {
+	root     string
+	patterns []ignorePattern
+	// maxDepth is the deepest directory level that is listed, counting the
+	// themes directory as 0; negative means unlimited
+	maxDepth int
+}
+
+// newScanFilter builds the filter for root from the default patterns, the
+// root's ignore file and the MAX_DEPTH environment variable
+func newScanFilter(root string) (*scanFilter, error) {
+	f := &scanFilter{root: root, maxDepth: -1}
+	for _, p := range defaultIgnorePatterns {
+		f.add(p)
+	}
+
+	if depth := os.Getenv("MAX_DEPTH"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil {
+			return f, err
+		}
+		f.maxDepth = n
+	}
+
+	file, err := os.Open(filepath.Join(root, ignoreFileName))
+	if os.IsNotExist(err) {
+		return f, nil
+	} else if err != nil {
+		return f, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		f.add(scanner.Text())
+	}
+
+	return f, scanner.Err()
+}
+
+// add parses a single gitignore-style pattern line
+func (f *scanFilter) add(line string) {
+	line = strings.TrimRight(line, " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// Patterns without a slash match at any level, others relative to the root
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	p.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+
+	f.patterns = append(f.patterns, p)
+}
+
+// ignored reports whether the entry at abs should be left out of the list
+func (f *scanFilter) ignored(abs string, isDir bool) bool {
+	rel, err := filepath.Rel(f.root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+
+	if isDir && f.maxDepth >= 0 && len(segments) > f.maxDepth {
+		return true
+	}
+
+	// An entry inside an ignored directory is ignored as well
+	for i := 1; i <= len(segments); i++ {
+		if f.match(segments[:i], isDir || i < len(segments)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match applies the patterns in order, the last matching one wins
+func (f *scanFilter) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, p := range f.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, segments) {
+			ignored = !p.negate
+		}
+	}
+
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where **
+// stands for any number of directories
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
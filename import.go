@@ -0,0 +1,972 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// importFormat reads another terminal's color scheme into #rrggbb colors
+// keyed by slot, e.g. "normal.red". Formats of whole collections set
+// parseAll instead of parse.
+type importFormat struct {
+	// exts are the file extensions the format is recognized by
+	exts     []string
+	parse    func(content []byte) (map[string]string, error)
+	parseAll func(content []byte) ([]importedScheme, error)
+	// listed schemes are shown along with the themes when they're found in
+	// a themes directory, and converted when previewed or applied
+	listed bool
+	// dir is the directory below the themes directory that collections are
+	// imported into, the themes directory itself when empty
+	dir string
+	// files are the extensions of the files read from a directory of
+	// schemes, exts when empty
+	files []string
+}
+
+// importedScheme is a named scheme of a collection
+type importedScheme struct {
+	name   string
+	colors map[string]string
+}
+
+// importFormats are the supported sources, keyed by the name given to --from
+var importFormats = map[string]importFormat{
+	"iterm2":           {exts: []string{".itermcolors"}, parse: parseITerm2, listed: true},
+	"base16":           {exts: []string{".yaml", ".yml"}, parse: parseBase16, listed: true},
+	"alacritty-yaml":   {exts: []string{".yaml", ".yml"}, parse: parseAlacrittyYAML, listed: true},
+	"xresources":       {exts: []string{".xresources", ".xdefaults"}, parse: parseXresources},
+	"windows-terminal": {exts: []string{".json"}, parseAll: parseWindowsTerminal},
+	"gogh":             {parseAll: parseGogh, dir: "gogh", files: []string{".yml", ".yaml", ".json"}},
+	"pywal":            {parse: parsePywal},
+	"kitty":            {exts: []string{".conf"}, parseAll: parseKitty, dir: "kitty"},
+}
+
+// runImport converts a color scheme of another terminal into an Alacritty
+// theme in the themes directory, or every scheme of a collection:
+// import <file|dir|url> [--from format] [--name name] [--force]
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	from := flags.String("from", "", "source format ("+strings.Join(importFormatNames(), ", ")+"), detected from the file extension by default")
+	name := flags.String("name", "", "name of the theme, the file name by default, or of the directory several schemes are imported into")
+	force := flags.Bool("force", false, "overwrite an existing theme")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage("import")
+	}
+	file := positional[0]
+
+	format, err := detectImportFormat(file, *from)
+	if err != nil {
+		return err
+	}
+	if format.parseAll != nil {
+		return importCollection(file, format, *name, *force)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	colors, err := format.parse(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if missing := missingSlot(colors); missing != "" {
+		return fmt.Errorf("%s: missing color %s", file, missing)
+	}
+
+	themesDir, _, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	if themesDir == "" {
+		return fmt.Errorf("no themes directory, set THEMES_DIR or themes_dir in alacritheme's config")
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return importTheme(themesDir, *name, colors, *force)
+}
+
+// importTheme writes colors as the theme called name into themesDir and
+// prints its path. An existing theme is only replaced with force.
+func importTheme(themesDir, name string, colors map[string]string, force bool) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("--name: %q can't be used as a theme name", name)
+	}
+	target := filepath.Join(themesDir, strings.TrimSuffix(name, ".toml")+".toml")
+	if fileExists(target) && !force {
+		return fmt.Errorf("%s already exists, pass --force to overwrite it or --name to pick another name", target)
+	}
+
+	content, err := encodeColors(colors)
+	if err != nil {
+		return err
+	}
+
+	trace("writing %s", target)
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return err
+	}
+
+	result("%s\n", target)
+	return nil
+}
+
+// importCollection converts every scheme of a collection, read from a file,
+// the files of a directory or a URL, into themes named after their scheme in
+// the directory called name, or the format's directory, below the themes
+// directory. Existing themes are only replaced with force.
+func importCollection(src string, format importFormat, name string, force bool) error {
+	themesDir, _, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	if themesDir == "" {
+		return fmt.Errorf("no themes directory, set THEMES_DIR or themes_dir in alacritheme's config")
+	}
+
+	exts := format.files
+	if len(exts) == 0 {
+		exts = format.exts
+	}
+	contents, err := readCollection(src, exts)
+	if err != nil {
+		return err
+	}
+
+	var schemes []importedScheme
+	for file, content := range contents {
+		parsed, err := format.parseAll(content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		// Schemes without a name of their own are named after their file
+		for i := range parsed {
+			if parsed[i].name == "" {
+				parsed[i].name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			}
+		}
+		schemes = append(schemes, parsed...)
+	}
+	if len(schemes) == 0 {
+		return fmt.Errorf("%s contains no schemes", src)
+	}
+	sort.Slice(schemes, func(a, b int) bool { return schemes[a].name < schemes[b].name })
+
+	// A single scheme is imported like the schemes of other formats, named
+	// after it
+	if len(schemes) == 1 && len(contents) == 1 {
+		if missing := missingSlot(schemes[0].colors); missing != "" {
+			return fmt.Errorf("%s: missing color %s", src, missing)
+		}
+		if name == "" {
+			// Named after the file already when the scheme has no name
+			if name, err = themeFileName(schemes[0].name); err != nil {
+				return err
+			}
+		}
+		return importTheme(themesDir, name, schemes[0].colors, force)
+	}
+
+	if name == "" {
+		name = format.dir
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("--name: %q can't be used as a directory name", name)
+	}
+	dir := filepath.Join(themesDir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var imported, existing, incomplete, duplicate int
+	written := make(map[string]bool)
+	for _, scheme := range schemes {
+		file, err := themeFileName(scheme.name)
+		if err != nil {
+			trace("skipping a scheme: %v", err)
+			incomplete++
+			continue
+		}
+		if missing := missingSlot(scheme.colors); missing != "" {
+			trace("skipping %s, it has no color for %s", scheme.name, missing)
+			incomplete++
+			continue
+		}
+		// Names that only differ in punctuation end up with the same file
+		if written[file] {
+			trace("skipping %s, %s was already written", scheme.name, file)
+			duplicate++
+			continue
+		}
+		written[file] = true
+
+		target := filepath.Join(dir, file)
+		if fileExists(target) && !force {
+			trace("keeping %s, it already exists", target)
+			existing++
+			continue
+		}
+
+		content, err := encodeColors(scheme.colors)
+		if err != nil {
+			return err
+		}
+		content = append([]byte(fmt.Sprintf("# Colors (%s)\n", scheme.name)), content...)
+		trace("writing %s", target)
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+		imported++
+	}
+
+	info("Imported %d themes into ", imported)
+	result("%s\n", dir)
+	if existing > 0 {
+		info("Kept %d existing themes, pass --force to replace them\n", existing)
+	}
+	if incomplete > 0 {
+		info("Skipped %d incomplete schemes, run with --verbose to see which\n", incomplete)
+	}
+	if duplicate > 0 {
+		info("Skipped %d schemes named like another one, run with --verbose to see which\n", duplicate)
+	}
+	return nil
+}
+
+// readCollection reads a collection from a URL, a file or the files of a
+// directory with one of exts, keyed by where they were read from. The
+// themes directory of a repository is read when given its root.
+func readCollection(src string, exts []string) (map[string][]byte, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		u, err := rawThemeURL(src)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fetch(u.String())
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{src: content}, nil
+	}
+
+	stat, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{src}
+	if stat.IsDir() {
+		if stat, err := os.Stat(filepath.Join(src, "themes")); err == nil && stat.IsDir() {
+			src = filepath.Join(src, "themes")
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			for _, e := range exts {
+				if ext == e && !entry.IsDir() {
+					files = append(files, filepath.Join(src, entry.Name()))
+				}
+			}
+		}
+	}
+
+	contents := make(map[string][]byte)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		contents[file] = content
+	}
+
+	return contents, nil
+}
+
+// missingSlot returns the first slot colors has no color for, or an empty
+// string when it's complete
+func missingSlot(colors map[string]string) string {
+	for _, slot := range (ColorScheme{}).slots() {
+		if _, ok := colors[slot.Key]; !ok {
+			return slot.Key
+		}
+	}
+
+	return ""
+}
+
+// encodeColors writes colors keyed by slot as an Alacritty theme
+func encodeColors(colors map[string]string) ([]byte, error) {
+	theme := make(map[string]interface{})
+	for key, color := range colors {
+		setColor(theme, key, color)
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(theme); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// importFormatNames returns the names of the supported formats in a stable
+// order, for usage messages
+func importFormatNames() []string {
+	names := make([]string, 0, len(importFormats))
+	for name := range importFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// detectImportFormat returns the format called name, or the one matching
+// the extension of file when name is empty. When several formats share the
+// extension, the first one that reads a complete scheme from file wins.
+func detectImportFormat(file, name string) (importFormat, error) {
+	if name != "" {
+		format, ok := importFormats[name]
+		if !ok {
+			return importFormat{}, fmt.Errorf("unknown format %q, supported formats are %s", name, strings.Join(importFormatNames(), ", "))
+		}
+		return format, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == "" {
+		// Xresources and Xdefaults are often named without the leading dot
+		ext = "." + strings.ToLower(filepath.Base(file))
+	}
+	formats := formatsFor(ext, false)
+	switch len(formats) {
+	case 0:
+		return importFormat{}, fmt.Errorf("can't tell the format of %s, pass --from (%s)", file, strings.Join(importFormatNames(), ", "))
+	case 1:
+		return formats[0], nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return importFormat{}, err
+	}
+	for _, format := range formats {
+		if colors, err := format.parse(content); err == nil && missingSlot(colors) == "" {
+			return format, nil
+		}
+	}
+	return formats[0], nil
+}
+
+// formatsFor returns the formats of files with the extension ext, only the
+// ones listed in themes directories if listed is set, by name
+func formatsFor(ext string, listed bool) []importFormat {
+	var formats []importFormat
+	for _, name := range importFormatNames() {
+		format := importFormats[name]
+		if listed && !format.listed {
+			continue
+		}
+		for _, e := range format.exts {
+			if ext == e {
+				formats = append(formats, format)
+			}
+		}
+	}
+
+	return formats
+}
+
+// parseITerm2 reads an iTerm2 .itermcolors property list, whose colors are
+// dictionaries of red, green and blue components between 0 and 1
+func parseITerm2(content []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var root interface{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("not a property list: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "dict" {
+			if root, err = plistValue(decoder, start); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	entries, _ := root.(map[string]interface{})
+	names := map[string]string{
+		"Background Color": "primary.background",
+		"Foreground Color": "primary.foreground",
+	}
+	for i, key := range ansiKeys {
+		names[fmt.Sprintf("Ansi %d Color", i)] = key
+	}
+
+	colors := make(map[string]string)
+	for name, key := range names {
+		components, ok := entries[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		r, _ := components["Red Component"].(float64)
+		g, _ := components["Green Component"].(float64)
+		b, _ := components["Blue Component"].(float64)
+		colors[key] = colorful.Color{R: r, G: g, B: b}.Clamped().Hex()
+	}
+
+	return colors, nil
+}
+
+// plistValue decodes the property list element opened by start into maps,
+// floats and strings, which is all .itermcolors files use
+func plistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := plistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			}
+		}
+	case "real", "integer":
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	default:
+		var text string
+		err := decoder.DecodeElement(&text, &start)
+		return text, err
+	}
+}
+
+// base16Slots maps base16 colors to terminal slots the way base16-shell does
+var base16Slots = map[string][]string{
+	"base00": {"primary.background", "normal.black"},
+	"base03": {"bright.black"},
+	"base05": {"primary.foreground", "normal.white"},
+	"base07": {"bright.white"},
+	"base08": {"normal.red", "bright.red"},
+	"base0A": {"normal.yellow", "bright.yellow"},
+	"base0B": {"normal.green", "bright.green"},
+	"base0C": {"normal.cyan", "bright.cyan"},
+	"base0D": {"normal.blue", "bright.blue"},
+	"base0E": {"normal.magenta", "bright.magenta"},
+}
+
+// base24Slots are the bright colors base24 schemes add, which replace the
+// ones base16 repeats from the normal colors
+var base24Slots = map[string][]string{
+	"base12": {"bright.red"},
+	"base13": {"bright.yellow"},
+	"base14": {"bright.green"},
+	"base15": {"bright.cyan"},
+	"base16": {"bright.blue"},
+	"base17": {"bright.magenta"},
+}
+
+// parseBase16 reads a base16 or base24 scheme. Both the flat format and the
+// newer one with a palette section are simple enough to read line by line.
+func parseBase16(content []byte) (map[string]string, error) {
+	fields, err := yamlFields(content)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for key, value := range fields {
+		if len(key) == 6 && strings.HasPrefix(key, "base") {
+			key = key[:5] + strings.ToUpper(key[5:])
+		}
+		values[key] = value
+	}
+
+	colors := make(map[string]string)
+	for _, slots := range []map[string][]string{base16Slots, base24Slots} {
+		for key, targets := range slots {
+			value, ok := values[key]
+			if !ok {
+				continue
+			}
+
+			c, err := parseColor(base16Value(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %s", key)
+			}
+			for _, slot := range targets {
+				colors[slot] = c.Hex()
+			}
+		}
+	}
+
+	return colors, nil
+}
+
+// yamlFields reads the "key: value" lines of a flat YAML file, or of its
+// nested sections, which is all color scheme files use
+func yamlFields(content []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = value
+	}
+
+	return fields, scanner.Err()
+}
+
+// yamlString extracts a YAML value, which may be quoted and followed by a
+// comment
+func yamlString(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		value, _, _ = strings.Cut(value[1:], value[:1])
+	} else {
+		value, _, _ = strings.Cut(value, " ")
+	}
+
+	return value
+}
+
+// base16Value extracts the color of a base16 value, which is written with or
+// without #
+func base16Value(value string) string {
+	return "#" + strings.TrimPrefix(yamlString(value), "#")
+}
+
+// parseGogh reads Gogh schemes: one of the YAML files of its themes
+// directory, or its themes.json with every scheme in an array
+func parseGogh(content []byte) ([]importedScheme, error) {
+	trimmed := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(trimmed, []byte("[")) && !bytes.HasPrefix(trimmed, []byte("{")) {
+		fields, err := yamlFields(content)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fields {
+			fields[key] = yamlString(value)
+		}
+		scheme, err := goghScheme(fields)
+		return []importedScheme{scheme}, err
+	}
+
+	var entries []map[string]interface{}
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(trimmed, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	} else if err := json.Unmarshal(trimmed, &entries); err != nil {
+		return nil, err
+	}
+
+	schemes := make([]importedScheme, 0, len(entries))
+	for _, entry := range entries {
+		fields := make(map[string]string)
+		for key, value := range entry {
+			if s, ok := value.(string); ok {
+				fields[key] = s
+			}
+		}
+		scheme, err := goghScheme(fields)
+		if err != nil {
+			return nil, err
+		}
+		schemes = append(schemes, scheme)
+	}
+
+	return schemes, nil
+}
+
+// goghScheme maps the fields of a Gogh scheme, color_01 to color_16 in ANSI
+// order, background, foreground and cursor, to slots
+func goghScheme(fields map[string]string) (importedScheme, error) {
+	scheme := importedScheme{name: fields["name"], colors: make(map[string]string)}
+	keys := map[string]string{
+		"background": "primary.background",
+		"foreground": "primary.foreground",
+		"cursor":     "cursor.cursor",
+	}
+	for i, key := range ansiKeys {
+		keys[fmt.Sprintf("color_%02d", i+1)] = key
+	}
+
+	for field, key := range keys {
+		value, ok := fields[field]
+		if !ok || value == "" {
+			continue
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return scheme, fmt.Errorf("%s: invalid color %s: %s", scheme.name, field, value)
+		}
+		scheme.colors[key] = c.Hex()
+	}
+
+	return scheme, nil
+}
+
+// parseXresources reads foreground, background, cursorColor and color0 to
+// color15 from X resources, whatever their class (*.color0, URxvt*color0,
+// ...), with #define constants substituted
+func parseXresources(content []byte) (map[string]string, error) {
+	defines := make(map[string]string)
+	colors := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "#define" {
+			defines[fields[1]] = fields[2]
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		resource, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name := resource[strings.LastIndexAny(resource, ".*")+1:]
+		value = strings.TrimSpace(value)
+		// Constants may be defined in terms of others, give up on cycles
+		for i := 0; i < len(defines); i++ {
+			defined, ok := defines[value]
+			if !ok {
+				break
+			}
+			value = defined
+		}
+
+		var key string
+		switch {
+		case name == "foreground":
+			key = "primary.foreground"
+		case name == "background":
+			key = "primary.background"
+		case name == "cursorColor":
+			key = "cursor.cursor"
+		case strings.HasPrefix(name, "color"):
+			n, err := strconv.Atoi(strings.TrimPrefix(name, "color"))
+			if err != nil || n < 0 || n >= len(ansiKeys) {
+				continue
+			}
+			key = ansiKeys[n]
+		default:
+			continue
+		}
+
+		c, err := parseXColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %s: %s", resource, value)
+		}
+		colors[key] = c.Hex()
+	}
+
+	return colors, scanner.Err()
+}
+
+// parseXColor parses a color as X writes it: #rgb, #rrggbb, or
+// rgb:r/g/b with one to four hex digits per component
+func parseXColor(s string) (colorful.Color, error) {
+	rest, ok := strings.CutPrefix(strings.ToLower(s), "rgb:")
+	if !ok {
+		return parseColor(s)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 {
+		return colorful.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	var components [3]float64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 16, 16)
+		if err != nil || len(part) == 0 || len(part) > 4 {
+			return colorful.Color{}, fmt.Errorf("invalid color %q", s)
+		}
+		// Each component is scaled to its number of digits, f/f/f is white
+		components[i] = float64(n) / float64(uint64(1)<<(4*len(part))-1)
+	}
+
+	return colorful.Color{R: components[0], G: components[1], B: components[2]}, nil
+}
+
+// parseAlacrittyYAML reads the colors of a theme or config of Alacritty
+// before 0.13, which used YAML. The colors may also be an alias of one of
+// several anchored schemes, as in "colors: *dracula".
+func parseAlacrittyYAML(content []byte) (map[string]string, error) {
+	// Values by their dotted path, and the paths of anchored mappings
+	values := make(map[string]string)
+	anchors := make(map[string]string)
+	aliases := make(map[string]string)
+
+	var path []string
+	var indents []int
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			path, indents = path[:len(path)-1], indents[:len(indents)-1]
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		full := strings.Join(append(path[:len(path):len(path)], key), ".")
+		value = yamlString(value)
+		switch {
+		case value == "" || strings.HasPrefix(value, "&"):
+			if anchor, ok := strings.CutPrefix(value, "&"); ok {
+				anchors[anchor] = full
+			}
+			path, indents = append(path, key), append(indents, indent)
+		case strings.HasPrefix(value, "*"):
+			aliases[full] = value[1:]
+		default:
+			values[full] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	prefix := "colors."
+	if anchor, ok := aliases["colors"]; ok {
+		prefix = anchors[anchor] + "."
+	}
+
+	colors := make(map[string]string)
+	for full, value := range values {
+		slot, ok := strings.CutPrefix(full, prefix)
+		if !ok || strings.Count(slot, ".") != 1 {
+			continue
+		}
+
+		// Alacritty also accepts the colors of the cell under the cursor
+		if value == "CellForeground" || value == "CellBackground" {
+			colors[slot] = value
+			continue
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %s: %s", slot, value)
+		}
+		colors[slot] = c.Hex()
+	}
+
+	return colors, nil
+}
+
+// parseKitty reads a kitty theme, named by its "## name:" header like the
+// themes of the kitty-themes repository are
+func parseKitty(content []byte) ([]importedScheme, error) {
+	keys := map[string]string{
+		"background":           "primary.background",
+		"foreground":           "primary.foreground",
+		"cursor":               "cursor.cursor",
+		"cursor_text_color":    "cursor.text",
+		"selection_background": "selection.background",
+		"selection_foreground": "selection.text",
+	}
+	for i, key := range ansiKeys {
+		keys[fmt.Sprintf("color%d", i)] = key
+	}
+
+	scheme := importedScheme{colors: make(map[string]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if name, ok := strings.CutPrefix(line, "## name:"); ok {
+			scheme.name = strings.TrimSpace(name)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, ok := keys[fields[0]]
+		// "none" leaves the color to kitty, e.g. reversing the cell's colors
+		if !ok || fields[1] == "none" || fields[1] == "background" || fields[1] == "foreground" {
+			continue
+		}
+
+		c, err := parseColor(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %s: %s", fields[0], fields[1])
+		}
+		scheme.colors[key] = c.Hex()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return []importedScheme{scheme}, nil
+}
+
+// parseWindowsTerminal reads the schemes of Windows Terminal's
+// settings.json, an array of schemes, or a single scheme as written by
+// export --to windows-terminal. Comments and trailing commas, which Windows
+// Terminal allows, are ignored.
+func parseWindowsTerminal(content []byte) ([]importedScheme, error) {
+	content = bytes.TrimSpace(stripJSONComments(content))
+
+	var schemes []windowsTerminalScheme
+	if bytes.HasPrefix(content, []byte("[")) {
+		if err := json.Unmarshal(content, &schemes); err != nil {
+			return nil, err
+		}
+	} else {
+		var settings struct {
+			windowsTerminalScheme
+			Schemes []windowsTerminalScheme `json:"schemes"`
+		}
+		if err := json.Unmarshal(content, &settings); err != nil {
+			return nil, err
+		}
+		schemes = settings.Schemes
+		if len(schemes) == 0 && settings.Background != "" {
+			schemes = append(schemes, settings.windowsTerminalScheme)
+		}
+	}
+
+	imported := make([]importedScheme, 0, len(schemes))
+	for _, s := range schemes {
+		colors := make(map[string]string)
+		for key, value := range map[string]string{
+			"primary.background":   s.Background,
+			"primary.foreground":   s.Foreground,
+			"normal.black":         s.Black,
+			"normal.red":           s.Red,
+			"normal.green":         s.Green,
+			"normal.yellow":        s.Yellow,
+			"normal.blue":          s.Blue,
+			"normal.magenta":       s.Purple,
+			"normal.cyan":          s.Cyan,
+			"normal.white":         s.White,
+			"bright.black":         s.BrightBlack,
+			"bright.red":           s.BrightRed,
+			"bright.green":         s.BrightGreen,
+			"bright.yellow":        s.BrightYellow,
+			"bright.blue":          s.BrightBlue,
+			"bright.magenta":       s.BrightPurple,
+			"bright.cyan":          s.BrightCyan,
+			"bright.white":         s.BrightWhite,
+			"cursor.cursor":        s.CursorColor,
+			"selection.background": s.SelectionBackground,
+		} {
+			if value == "" {
+				continue
+			}
+			c, err := parseColor(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid color %s: %s", s.Name, key, value)
+			}
+			colors[key] = c.Hex()
+		}
+		imported = append(imported, importedScheme{name: s.Name, colors: colors})
+	}
+
+	return imported, nil
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas from
+// JSON, leaving strings alone
+func stripJSONComments(content []byte) []byte {
+	out := make([]byte, 0, len(content))
+	inString := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				out = append(out, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a comma that only whitespace separates from the end
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		in      string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:   "iterm2",
+			format: "iterm2",
+			in: `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Background Color</key>
+	<dict>
+		<key>Color Space</key>
+		<string>sRGB</string>
+		<key>Blue Component</key>
+		<real>0.2</real>
+		<key>Green Component</key>
+		<real>0.0</real>
+		<key>Red Component</key>
+		<real>1</real>
+	</dict>
+	<key>Ansi 9 Color</key>
+	<dict>
+		<key>Blue Component</key>
+		<integer>0</integer>
+		<key>Green Component</key>
+		<real>1.5</real>
+		<key>Red Component</key>
+		<real>0</real>
+	</dict>
+</dict>
+</plist>
+`,
+			want: map[string]string{"primary.background": "#ff0033", "bright.red": "#00ff00"},
+		},
+		{
+			name:   "base16",
+			format: "base16",
+			in:     "scheme: \"Test\"\nbase00: \"101010\" # background\nbase05: '#e0e0e0'\nbase08: ff0000\n",
+			want: map[string]string{
+				"primary.background": "#101010", "normal.black": "#101010",
+				"primary.foreground": "#e0e0e0", "normal.white": "#e0e0e0",
+				"normal.red": "#ff0000", "bright.red": "#ff0000",
+			},
+		},
+		{
+			name:   "base24 with a palette section",
+			format: "base16",
+			in:     "system: \"base24\"\npalette:\n  base08: \"#ff0000\"\n  base12: \"#ff8080\"\n  base0a: \"#ffff00\"\n",
+			want: map[string]string{
+				"normal.red": "#ff0000", "bright.red": "#ff8080",
+				"normal.yellow": "#ffff00", "bright.yellow": "#ffff00",
+			},
+		},
+		{
+			name:    "base16 with an invalid color",
+			format:  "base16",
+			in:      "base08: \"nothex\"\n",
+			wantErr: "invalid color base08",
+		},
+		{
+			name:   "alacritty-yaml",
+			format: "alacritty-yaml",
+			in:     "font:\n  size: 12\ncolors:\n  primary:\n    background: '#1e1e2e' # base\n    foreground: \"0xcdd6f4\"\n  cursor:\n    text: CellBackground\n",
+			want:   map[string]string{"primary.background": "#1e1e2e", "primary.foreground": "#cdd6f4", "cursor.text": "CellBackground"},
+		},
+		{
+			name:   "alacritty-yaml with anchored schemes",
+			format: "alacritty-yaml",
+			in:     "schemes:\n  light: &light\n    primary:\n      background: '#ffffff'\n  dark: &dark\n    primary:\n      background: '#000000'\ncolors: *dark\n",
+			want:   map[string]string{"primary.background": "#000000"},
+		},
+		{
+			name:   "xresources",
+			format: "xresources",
+			in:     "! comment\n#define bg #282828\n#define base bg\n*.background: base\nURxvt*color1: rgb:f/0/0\n*color9: rgb:ffff/8080/0000\n*.cursorColor: #fff\n*color16: #123456\nURxvt.font: xft:mono\n",
+			want:   map[string]string{"primary.background": "#282828", "normal.red": "#ff0000", "bright.red": "#ff8000", "cursor.cursor": "#ffffff"},
+		},
+		{
+			name:    "xresources with an invalid color",
+			format:  "xresources",
+			in:      "*.color0: rgb:12345/0/0\n",
+			wantErr: "invalid color *.color0",
+		},
+		{
+			name:   "pywal",
+			format: "pywal",
+			in:     `{"special": {"background": "#101010", "foreground": "#e0e0e0", "cursor": "#e0e0e0"}, "colors": {"color1": "#ff0000", "color15": "#ffffff"}}`,
+			want: map[string]string{
+				"primary.background": "#101010", "primary.foreground": "#e0e0e0", "cursor.cursor": "#e0e0e0",
+				"normal.red": "#ff0000", "bright.white": "#ffffff",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := importFormats[tt.format].parse([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportParseAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		in      string
+		want    []importedScheme
+		wantErr string
+	}{
+		{
+			name:   "gogh yaml",
+			format: "gogh",
+			in:     "name: 'Test'\ncolor_01: '#000000'\ncolor_10: \"#ff0000\"\nbackground: '#101010'\ncursor: ''\n",
+			want:   []importedScheme{{name: "Test", colors: map[string]string{"normal.black": "#000000", "bright.red": "#ff0000", "primary.background": "#101010"}}},
+		},
+		{
+			name:   "gogh themes.json",
+			format: "gogh",
+			in:     `[{"name": "A", "color_02": "#ff0000"}, {"name": "B", "foreground": "#ffffff", "hash": 1}]`,
+			want: []importedScheme{
+				{name: "A", colors: map[string]string{"normal.red": "#ff0000"}},
+				{name: "B", colors: map[string]string{"primary.foreground": "#ffffff"}},
+			},
+		},
+		{
+			name:    "gogh with an invalid color",
+			format:  "gogh",
+			in:      `{"name": "A", "color_02": "red-ish"}`,
+			wantErr: "A: invalid color color_02",
+		},
+		{
+			name:   "kitty",
+			format: "kitty",
+			in:     "## name: Test\n# color0 #ffffff\nbackground #101010\ncolor1   #ff0000\ncursor none\nselection_foreground background\nfont_size 12\n",
+			want:   []importedScheme{{name: "Test", colors: map[string]string{"primary.background": "#101010", "normal.red": "#ff0000"}}},
+		},
+		{
+			name:   "windows terminal settings",
+			format: "windows-terminal",
+			in: `// settings.json
+{
+	"profiles": {"list": []},
+	"schemes": [
+		{
+			"name": "Test", // the one scheme
+			"background": "#101010",
+			"purple": "#ff00ff",
+			"brightBlack": "#808080",
+			/* optional */ "cursorColor": "#ffffff",
+		},
+	],
+}`,
+			want: []importedScheme{{name: "Test", colors: map[string]string{
+				"primary.background": "#101010", "normal.magenta": "#ff00ff", "bright.black": "#808080", "cursor.cursor": "#ffffff",
+			}}},
+		},
+		{
+			name:   "windows terminal scheme",
+			format: "windows-terminal",
+			in:     `{"name": "Test", "background": "#101010", "url": "http://example.com/a//b"}`,
+			want:   []importedScheme{{name: "Test", colors: map[string]string{"primary.background": "#101010"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := importFormats[tt.format].parseAll([]byte(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
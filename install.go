@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// runInstall downloads a theme from a URL into the themes directory:
+// install <url> [--name name] [--apply] [--force]
+func runInstall(args []string) error {
+	flags := flag.NewFlagSet("install", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the theme, the file name in the URL by default")
+	apply := flags.Bool("apply", false, "apply the theme once it's installed")
+	force := flags.Bool("force", false, "overwrite an existing theme")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage("install")
+	}
+
+	themeURL, err := rawThemeURL(positional[0])
+	if err != nil {
+		return err
+	}
+
+	themesDir, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	if themesDir == "" {
+		return fmt.Errorf("no themes directory, set THEMES_DIR or themes_dir in alacritheme's config")
+	}
+
+	content, err := fetch(themeURL.String())
+	if err != nil {
+		return err
+	}
+	if err := validateScheme(content); err != nil {
+		return fmt.Errorf("%s is not a valid theme: %w", positional[0], err)
+	}
+
+	if *name == "" {
+		*name = path.Base(themeURL.Path)
+	}
+	file, err := themeFileName(*name)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(themesDir, file)
+	if fileExists(target) && !*force {
+		return fmt.Errorf("%s already exists, pass --force to overwrite it or --name to pick another name", target)
+	}
+
+	if err := os.MkdirAll(themesDir, 0755); err != nil {
+		return err
+	}
+	trace("writing %s", target)
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return err
+	}
+	source := themeSource{Origin: themeURL.String(), URL: themeURL.String(), SHA256: contentHash(content)}
+	if err := recordSources(map[string]themeSource{target: source}); err != nil {
+		return err
+	}
+
+	info("Installed ")
+	result("%s\n", target)
+	if !*apply {
+		return nil
+	}
+
+	_, current, err := appliedTheme(configFile)
+	if err != nil {
+		return err
+	}
+	if err := setTheme(configFile, current, target); err != nil {
+		return err
+	}
+	info("Applied %s to %s\n", file, configFile)
+	return nil
+}
+
+// rawThemeURL parses an http(s) URL, pointing GitHub file pages at the raw
+// file so the page's HTML isn't downloaded instead
+func rawThemeURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", rawURL)
+	}
+
+	// github.com/<owner>/<repo>/blob/<ref>/<path>
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+	if u.Host == "github.com" && len(parts) == 4 && parts[2] == "blob" {
+		u.Host = "raw.githubusercontent.com"
+		u.Path = "/" + parts[0] + "/" + parts[1] + "/" + parts[3]
+	}
+
+	return u, nil
+}
+
+// validateScheme checks that content is an Alacritty color scheme with at
+// least its primary colors, and that every color it sets is one
+func validateScheme(content []byte) error {
+	var scheme ColorScheme
+	if err := toml.Unmarshal(content, &scheme); err != nil {
+		return err
+	}
+
+	for _, slot := range scheme.slots() {
+		if slot.Color == "" && strings.HasPrefix(slot.Key, "primary.") {
+			return fmt.Errorf("missing colors.%s", slot.Key)
+		}
+		if _, err := parseColor(slot.Color); slot.Color != "" && err != nil {
+			return fmt.Errorf("colors.%s: %q is not a color", slot.Key, slot.Color)
+		}
+	}
+
+	return nil
+}
+
+// themeFileName turns a theme name into a safe file name, keeping letters,
+// digits, dots, dashes and underscores, e.g. "Tokyo Night (storm)" ->
+// "Tokyo-Night-storm.toml"
+func themeFileName(name string) (string, error) {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSuffix(name, ".toml") {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			b.WriteRune(r)
+			dash = false
+		case !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	file := strings.Trim(b.String(), "-.")
+	if file == "" {
+		return "", fmt.Errorf("can't make a file name of %q, pass --name", name)
+	}
+	return file + ".toml", nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyActions are the TUI actions whose key can be changed in the [keys]
+// table of alacritheme's config, by name
+var keyActions = map[string]*key.Binding{
+	"toggle":     &toggleKey,
+	"pair":       &pairKey,
+	"compare":    &compareKey,
+	"groups":     &groupKey,
+	"add":        &addToGroupKey,
+	"references": &referencesKey,
+	"contrast":   &contrastKey,
+	"fix":        &fixKey,
+	"stats":      &statsKey,
+	"window":     &windowKey,
+	"edit":       &editKey,
+	"update":     &updateKey,
+	"view":       &viewKey,
+	"export":     &exportKey,
+	"undo":       &undoKey,
+	"redo":       &redoKey,
+}
+
+// reservedKeys navigate the list and can't be bound to actions
+var reservedKeys = []string{"q", "ctrl+c", "enter", "up", "down", "left", "right", "pgup", "pgdown", "j", "k", "h", "l", "/"}
+
+// keyMap translates pressed keys into the default key of the action they're
+// bound to; keys missing from it are passed through unchanged
+type keyMap map[string]string
+
+// translate returns the default key of the action bound to pressed, or an
+// empty string if pressed was moved to another key
+func (k keyMap) translate(pressed string) string {
+	if action, ok := k[pressed]; ok {
+		return action
+	}
+
+	return pressed
+}
+
+// bindKeys rebinds actions to the keys configured for them and returns the
+// keyMap for the TUI's key handling
+func bindKeys(keys map[string]string) (keyMap, error) {
+	// Apply in a fixed order, so a conflict always reports the same action
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	km := make(keyMap)
+	bound := make(map[string]string)
+	for _, name := range names {
+		binding, ok := keyActions[name]
+		if !ok {
+			return nil, fmt.Errorf("keys: unknown action %q, expected one of %s", name, strings.Join(keyActionNames(), ", "))
+		}
+
+		pressed := keys[name]
+		for _, reserved := range reservedKeys {
+			if pressed == reserved {
+				return nil, fmt.Errorf("keys: %q can't be bound to %s, it's used to navigate the list", pressed, name)
+			}
+		}
+		if other, ok := bound[pressed]; ok {
+			return nil, fmt.Errorf("keys: %q is bound to both %s and %s", pressed, other, name)
+		}
+		bound[pressed] = name
+
+		defaultKey := binding.Keys()[0]
+		km[pressed] = defaultKey
+		// The default key does nothing unless another action takes it over
+		if _, ok := km[defaultKey]; !ok {
+			km[defaultKey] = ""
+		}
+		binding.SetKeys(pressed)
+		binding.SetHelp(pressed, binding.Help().Desc)
+	}
+
+	// Actions keep their default key unless another action took it over
+	for name, binding := range keyActions {
+		if _, rebound := keys[name]; !rebound && bound[binding.Keys()[0]] != "" {
+			binding.SetEnabled(false)
+		}
+	}
+
+	return km, nil
+}
+
+// keyActionNames returns the names of the actions that can be rebound
+func keyActionNames() []string {
+	names := make([]string, 0, len(keyActions))
+	for name := range keyActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
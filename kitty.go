@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// kittyInclude is the file in kitty's config directory the kitty backend
+// writes the applied theme to, included from kitty.conf
+const kittyInclude = "alacritheme.conf"
+
+// kittyRemote returns the kitty binary and the arguments reaching the
+// running kitty through its remote control, which has to be allowed with
+// allow_remote_control. kitty sets KITTY_LISTEN_ON when it listens on a
+// socket, otherwise only windows of kitty itself can reach it.
+func kittyRemote() (string, []string, error) {
+	args := []string{"@"}
+	if socket := os.Getenv("KITTY_LISTEN_ON"); socket != "" {
+		args = append(args, "--to", socket)
+	} else if os.Getenv("KITTY_WINDOW_ID") == "" {
+		return "", nil, errors.New("no kitty socket found, run inside kitty or set listen_on in kitty.conf")
+	}
+	kitty, err := exec.LookPath("kitty")
+	if err != nil {
+		return "", nil, errors.New("kitty isn't installed or not in PATH")
+	}
+
+	return kitty, args, nil
+}
+
+// kittyCommand runs a remote control command in the running kitty
+func kittyCommand(args ...string) error {
+	kitty, remote, err := kittyRemote()
+	if err != nil {
+		return err
+	}
+
+	args = append(remote, args...)
+	trace("running kitty %s", strings.Join(args, " "))
+	if out, err := exec.Command(kitty, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("kitty @ %s: %s", args[len(remote)], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// kittySetColors applies an exported kitty theme to every window of the
+// running kitty. configured also changes the colors of windows opened later,
+// and the ones --reset goes back to.
+func kittySetColors(theme []byte, configured bool) error {
+	if _, _, err := kittyRemote(); err != nil {
+		return err
+	}
+	if dryRun {
+		info("Would apply the colors to kitty\n")
+		return nil
+	}
+
+	tmp, err := os.CreateTemp("", "alacritheme-*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(theme); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"set-colors", "--all"}
+	if configured {
+		args = append(args, "--configured")
+	}
+	return kittyCommand(append(args, tmp.Name())...)
+}
+
+// kittyConfigDir returns kitty's config directory
+func kittyConfigDir() (string, error) {
+	if dir := os.Getenv("KITTY_CONFIG_DIRECTORY"); dir != "" {
+		return dir, nil
+	}
+
+	config, err := configHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(config, "kitty"), nil
+}
+
+// kittyBackend previews themes in the running kitty with kitty @ set-colors,
+// and keeps the applied one in kittyInclude, included from kitty.conf, so
+// kitty starts with it too
+type kittyBackend struct {
+	previewed bool
+}
+
+func (b *kittyBackend) Name() string { return "kitty" }
+
+// Detect reports whether a running kitty can be reached
+func (b *kittyBackend) Detect() bool {
+	_, _, err := kittyRemote()
+	return err == nil
+}
+
+// theme returns the theme at path in kitty's format
+func (b *kittyBackend) theme(path string) ([]byte, error) {
+	scheme, err := readScheme(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportKitty(schemeName(path), scheme)
+}
+
+func (b *kittyBackend) Preview(path string) error {
+	if dryRun {
+		return nil
+	}
+	theme, err := b.theme(path)
+	if err != nil {
+		return err
+	}
+
+	b.previewed = true
+	return kittySetColors(theme, false)
+}
+
+// Apply writes the theme to kittyInclude, includes it from kitty.conf once,
+// and makes it the colors of the running kitty
+func (b *kittyBackend) Apply(path string) error {
+	if dryRun {
+		return nil
+	}
+	theme, err := b.theme(path)
+	if err != nil {
+		return err
+	}
+
+	dir, err := kittyConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	trace("writing %s", filepath.Join(dir, kittyInclude))
+	if err := writeConfig(filepath.Join(dir, kittyInclude), theme); err != nil {
+		return err
+	}
+	if err := includeKittyTheme(filepath.Join(dir, "kitty.conf")); err != nil {
+		return err
+	}
+
+	return kittySetColors(theme, true)
+}
+
+// Restore goes back to the colors kitty was configured with
+func (b *kittyBackend) Restore() error {
+	if dryRun || !b.previewed {
+		return nil
+	}
+
+	b.previewed = false
+	return kittyCommand("set-colors", "--all", "--reset")
+}
+
+// includeKittyTheme adds an include of kittyInclude to the end of the
+// kitty.conf at path, unless it has one, so it overrides earlier colors
+func includeKittyTheme(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	include := "include " + kittyInclude
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == include {
+			return nil
+		}
+	}
+
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	content = append(content, "\n# The theme applied by alacritheme\n"+include+"\n"...)
+	trace("writing %s", path)
+	return writeConfig(path, content)
+}
@@ -0,0 +1,34 @@
+package main
+
+import "flag"
+
+// runList prints the name of every theme, one per line, or its file with
+// --paths: list [--paths]
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	paths := flags.Bool("paths", false, "print the theme files instead of their names")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usage("list")
+	}
+
+	dirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	names, err := themeNames(dirs)
+	for _, name := range names {
+		if *paths {
+			if path, err := resolveTheme(dirs, name); err == nil {
+				name = path
+			}
+		}
+		result("%s\n", name)
+	}
+
+	return err
+}
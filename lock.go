@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// lockKeyPattern matches a color's path below [colors], e.g.
+// "primary.background" or "cursor.cursor"
+var lockKeyPattern = regexp.MustCompile(`^[a-z_]+(\.[a-z_]+)+$`)
+
+// withLocks returns config with the locked colors set. Colors in the config
+// itself take precedence over imported ones, so they win over every theme.
+// The colors table is copied, config is left unchanged.
+func withLocks(config map[string]interface{}, locks map[string]string) map[string]interface{} {
+	if len(locks) == 0 {
+		return config
+	}
+
+	updated := make(map[string]interface{}, len(config)+1)
+	for k, v := range config {
+		updated[k] = v
+	}
+	if colors, ok := config["colors"].(map[string]interface{}); ok {
+		updated["colors"] = copyTable(colors)
+	}
+
+	for key, color := range locks {
+		setColor(updated, key, color)
+	}
+
+	return updated
+}
+
+// readLocked reads the theme at path with the locked colors set, so the
+// preview shows what applying it results in
+func readLocked(path string, locks map[string]string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil || len(locks) == 0 {
+		return content, err
+	}
+
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		// Leave reporting the error to the preview
+		return content, nil
+	}
+
+	return toml.Marshal(withLocks(theme, locks))
+}
+
+func copyTable(table map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(table))
+	for k, v := range table {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = copyTable(nested)
+		}
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// runLock locks a color to a value for every theme applied, or lists the
+// locked colors: lock [<color> <value>]
+func runLock(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("lock", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	switch len(args) {
+	case 0:
+		keys := make([]string, 0, len(cfg.Locks))
+		for key := range cfg.Locks {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if len(keys) == 0 {
+			info("No colors are locked.\n")
+		}
+		for _, key := range keys {
+			result("%s = %s\n", key, cfg.Locks[key])
+		}
+		return nil
+	case 2:
+	default:
+		return usage("lock")
+	}
+
+	key, value := args[0], args[1]
+	if !lockKeyPattern.MatchString(key) {
+		return fmt.Errorf("%q is not a color, use its path below [colors], e.g. primary.background", key)
+	}
+	c, err := parseColor(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a color, use #rrggbb", value)
+	}
+
+	if cfg.Locks == nil {
+		cfg.Locks = make(map[string]string)
+	}
+	cfg.Locks[key] = c.Hex()
+	if err := saveAppConfig(cfg); err != nil {
+		return err
+	}
+
+	info("Locked %s to %s, it applies the next time a theme is applied.\n", key, c.Hex())
+	return nil
+}
+
+// runUnlock lets themes set a locked color again: unlock <color>
+func runUnlock(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("unlock", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usage("unlock")
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Locks[args[0]]; !ok {
+		return fmt.Errorf("%s is not locked", args[0])
+	}
+
+	delete(cfg.Locks, args[0])
+	if err := saveAppConfig(cfg); err != nil {
+		return err
+	}
+
+	info("Unlocked %s, it applies the next time a theme is applied.\n", args[0])
+	return nil
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
-	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -19,30 +28,212 @@ type model struct {
 	viewport     viewport.Model
 	items        []list.Item
 	themesDir    string
+	themesDirs   []string
 	windowSize   tea.WindowSizeMsg
 	ready        bool
 	err          error
-	configFile   string
-	originalToml []byte
-	tomlBackup   map[string]interface{}
 	lastSelected int
+	pairs        map[string]string
+	pairMark     string
+	currentTheme string
+	compare      bool
+	groups       map[string][]string
+	group        string
+	groupInput   textinput.Model
+	prompting    bool
+	scanner      *dirScanner
+	previewSeq   int
+	applySeq     int
+	previews     *previewTracker
+	filters      []*scanFilter
+	previewed    string
+	restored     bool
+	quit         bool
+	scripts      *scriptEngine
+	outdated     map[string]bool
+	locks        map[string]string
+	keys         keyMap
+	sortOrder    string
+	preview      previewConfig
+	export       exportConfig
+	// flat lists the themes of every subdirectory at once, by relative
+	// path, instead of browsing one directory at a time. browseDir is the
+	// subdirectory being browsed otherwise, below the themes directory at
+	// position browseRoot.
+	flat       bool
+	browseDir  string
+	browseRoot int
+	// registries are the remote theme sources from alacritheme's config,
+	// and registryThemes the themes they offer, listed after the local
+	// ones once scanDone, without the ones with fewer than minStars
+	registries     map[string]string
+	registryThemes []registryTheme
+	minStars       int
+	scanDone       bool
+	// alacritty applies themes to Alacritty, and backends to every
+	// terminal, Alacritty included
+	alacritty *alacrittyBackend
+	backends  []backend
+	// history are the themes previewed so far, oldest first, and
+	// historyPos the one undo and redo stepped to
+	history    []string
+	historyPos int
 }
 
 type item struct {
 	title       string
 	path        string
 	isDirectory bool
+	content     string
+	outdated    bool
+	// root is the position of the themes directory the item was listed
+	// from and source its label, shown when another directory has a theme
+	// of the same name
+	root      int
+	source    string
+	duplicate bool
+	modTime   time.Time
+	// remote is set for themes a registry offers that haven't been
+	// downloaded yet
+	remote *registryTheme
 }
 
-func (i item) Title() string       { return i.title }
-func (i item) Description() string { return i.path }
-func (i item) FilterValue() string { return i.title }
+func (i item) Title() string {
+	title := i.title
+	if i.remote != nil {
+		title += " ↓ remote"
+	}
+	if i.stars() > 0 {
+		title += fmt.Sprintf(" ★ %d", i.stars())
+	}
+	if i.duplicate {
+		title += " · " + i.source
+	}
+	if i.outdated {
+		title += " ↑ update available"
+	}
+	return title
+}
+
+// Description shows the theme's file, or the scheme it was converted from,
+// tagged with the themes directory or registry it comes from when
+// several are listed
+func (i item) Description() string {
+	path := i.path
+	if scheme := convertedSource(i.path); scheme != "" {
+		path = scheme
+	}
+
+	if i.source == "" {
+		return path
+	}
+	return "[" + i.source + "] " + path
+}
+
+// FilterValue matches the title first, then the source and file contents
+func (i item) FilterValue() string { return i.title + "\n" + i.source + "\n" + i.content }
+
+// stars is how popular a registry theme is, 0 for local themes
+func (i item) stars() int {
+	if i.remote == nil {
+		return 0
+	}
+
+	return i.remote.stars
+}
+
+// isLocalTheme reports whether the item is a theme file on disk, rather than
+// a directory or a theme that hasn't been downloaded from a registry yet
+func (i item) isLocalTheme() bool { return !i.isDirectory && i.remote == nil }
+
+// filterThemes fuzzy matches the term against theme names and falls back to a
+// case-insensitive substring match inside the theme files, so colors, comments
+// and author lines can be searched too
+func filterThemes(term string, targets []string) []list.Rank {
+	titles := make([]string, len(targets))
+	contents := make([]string, len(targets))
+	for i, target := range targets {
+		titles[i], contents[i], _ = strings.Cut(target, "\n")
+	}
+
+	ranks := list.DefaultFilter(term, titles)
+	matched := make(map[int]bool, len(ranks))
+	for _, r := range ranks {
+		matched[r.Index] = true
+	}
+
+	needles := []string{strings.ToLower(term)}
+	if hex, ok := strings.CutPrefix(needles[0], "#"); ok && hex != "" {
+		// older themes write colors as 0xRRGGBB
+		needles = append(needles, "0x"+hex)
+	}
+
+	for i, content := range contents {
+		if matched[i] {
+			continue
+		}
+
+		content = strings.ToLower(content)
+		for _, needle := range needles {
+			if strings.Contains(content, needle) {
+				ranks = append(ranks, list.Rank{Index: i})
+				break
+			}
+		}
+	}
+
+	return ranks
+}
 
 type filesLoadedMsg struct {
-	items []list.Item
+	scanner *dirScanner
+	items   []list.Item
+	err     error
+	done    bool
+}
+
+type previewReadyMsg struct {
+	seq     int
+	content string
+	err     error
+}
+
+// settleDelay is how long the cursor has to rest on a theme before it's
+// written to the config, so scrolling through the list doesn't rewrite the
+// file for every theme passed
+const settleDelay = 250 * time.Millisecond
+
+type selectionSettledMsg struct {
+	seq  int
+	path string
+}
+
+type updatesCheckedMsg struct {
+	outdated map[string]bool
+	err      error
+}
+
+type registriesListedMsg struct {
+	themes []registryTheme
+	err    error
+}
+
+type registrySettledMsg struct {
+	seq   int
+	theme registryTheme
+}
+
+type registryDownloadedMsg struct {
+	theme registryTheme
+	path  string
 	err   error
 }
 
+type themeUpdatedMsg struct {
+	path string
+	err  error
+}
+
 type themeSelectedMsg struct {
 	path string
 	err  error
@@ -75,11 +266,35 @@ type ColorScheme struct {
 			Cyan    string
 			White   string
 		}
+		// Cursor and Selection are optional, and may name cell colors
+		// such as "CellForeground" instead of a color
+		Cursor struct {
+			Text   string
+			Cursor string
+		}
+		Selection struct {
+			Text       string
+			Background string
+		}
+		FooterBar     uiColors `toml:"footer_bar"`
+		LineIndicator uiColors `toml:"line_indicator"`
 	}
 }
 
-// renderColorBox creates a scaled colored box with a label
-func renderColorBox(color, label string, boxWidth int) string {
+// uiColors is a foreground/background pair used by Alacritty's own UI, such
+// as the search and hint footer bar or the vi mode line indicator
+type uiColors struct {
+	Foreground string
+	Background string
+}
+
+func (c uiColors) isSet() bool {
+	return c.Foreground != "" || c.Background != ""
+}
+
+// renderColorBox creates a scaled colored box with a label, and the details
+// set in the preview config below it
+func renderColorBox(color, label string, boxWidth int, details string) string {
 	// Calculate sizes based on available width
 	labelStyle := lipgloss.NewStyle().
 		Width(boxWidth).
@@ -91,13 +306,25 @@ func renderColorBox(color, label string, boxWidth int) string {
 		Height(1).
 		Align(lipgloss.Center)
 
-	return fmt.Sprintf("%s\n%s",
+	// The hex value and its closest named color, truncated to the box
+	detailStyle := labelStyle.Faint(true).MaxWidth(boxWidth)
+
+	lines := []string{
 		boxStyle.Render(" "),
-		labelStyle.Render(lipgloss.NewStyle().Width(boxWidth).Render(label)))
+		labelStyle.Render(lipgloss.NewStyle().Width(boxWidth).Render(label)),
+	}
+	if details != "none" {
+		lines = append(lines, detailStyle.Render(color))
+	}
+	if details == "" || details == "all" {
+		lines = append(lines, detailStyle.Render(closestColorName(color)))
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 // renderColorPreview creates a dynamically scaled preview of the color scheme
-func renderColorPreview(content string, viewportWidth int) string {
+func renderColorPreview(content string, viewportWidth int, opts previewConfig) string {
 	var scheme ColorScheme
 	if err := toml.Unmarshal([]byte(content), &scheme); err != nil {
 		return fmt.Sprintf("Error parsing theme: %v", err)
@@ -105,7 +332,7 @@ func renderColorPreview(content string, viewportWidth int) string {
 
 	// Calculate dynamic sizes based on viewport
 	contentWidth := viewportWidth - 4 // Account for borders and padding
-	numColumns := 4
+	numColumns := opts.columns()
 	boxWidth := (contentWidth - (numColumns-1)*2) / numColumns // Account for spacing between boxes
 
 	// Define colors with their labels
@@ -147,9 +374,9 @@ func renderColorPreview(content string, viewportWidth int) string {
 	bgfg := bgfgStyle.Render(
 		lipgloss.JoinHorizontal(
 			lipgloss.Center,
-			renderColorBox(scheme.Colors.Primary.Background, "Background", boxWidth),
+			renderColorBox(scheme.Colors.Primary.Background, "Background", boxWidth, opts.Details),
 			strings.Repeat(" ", 2),
-			renderColorBox(scheme.Colors.Primary.Foreground, "Foreground", boxWidth),
+			renderColorBox(scheme.Colors.Primary.Foreground, "Foreground", boxWidth, opts.Details),
 		),
 	)
 
@@ -164,7 +391,7 @@ func renderColorPreview(content string, viewportWidth int) string {
 
 			row := make([]string, 0, numColumns)
 			for _, c := range colors[i:end] {
-				row = append(row, renderColorBox(c.color, c.name, boxWidth))
+				row = append(row, renderColorBox(c.color, c.name, boxWidth, opts.Details))
 			}
 			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Center, row...))
 		}
@@ -191,8 +418,7 @@ func renderColorPreview(content string, viewportWidth int) string {
 		Align(lipgloss.Center)
 
 	// Join all sections with proper spacing
-	return lipgloss.JoinVertical(
-		lipgloss.Center,
+	sections := []string{
 		titleStyle.Render("Theme Preview"),
 		"",
 		titleStyle.Render("Background/Foreground Colors"),
@@ -203,261 +429,1622 @@ func renderColorPreview(content string, viewportWidth int) string {
 		"",
 		titleStyle.Render("Bright Colors"),
 		bright,
-	)
+	}
+
+	// Footer bar and line indicator are optional, only show them when set
+	if scheme.Colors.FooterBar.isSet() || scheme.Colors.LineIndicator.isSet() {
+		ui := lipgloss.NewStyle().
+			Width(contentWidth).
+			Padding(1).
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("69")).
+			Render(lipgloss.JoinVertical(
+				lipgloss.Left,
+				renderUIColors(scheme.Colors.FooterBar, "Footer Bar", "Search: alacritheme", contentWidth-4),
+				"",
+				renderUIColors(scheme.Colors.LineIndicator, "Line Indicator", "[12/345]", contentWidth-4),
+			))
+
+		sections = append(sections, "", titleStyle.Render("UI Colors"), ui)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center, sections...)
+}
+
+// renderUIColors renders sample text in a UI color pair with a label, or
+// notes that Alacritty's default is used when the pair isn't set
+func renderUIColors(colors uiColors, label, sample string, width int) string {
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	if !colors.isSet() {
+		return labelStyle.Render(label) + "\n(Alacritty default)"
+	}
+
+	style := lipgloss.NewStyle().Width(width)
+	if colors.Foreground != "" {
+		style = style.Foreground(lipgloss.Color(colors.Foreground))
+	}
+	if colors.Background != "" {
+		style = style.Background(lipgloss.Color(colors.Background))
+	}
+
+	return labelStyle.Render(label) + "\n" + style.Render(sample)
+}
+
+var (
+	toggleKey     = key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "toggle light/dark"))
+	pairKey       = key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pair themes"))
+	compareKey    = key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "compare with current"))
+	groupKey      = key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "browse groups"))
+	addToGroupKey = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add to/remove from group"))
+	referencesKey = key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "show references"))
+	contrastKey   = key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "contrast report"))
+	fixKey        = key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "save contrast fix"))
+	statsKey      = key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "palette statistics"))
+	windowKey     = key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "try in new window"))
+	editKey       = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit theme"))
+	updateKey     = key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "update from upstream"))
+	viewKey       = key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "flat/folder view"))
+	exportKey     = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export theme"))
+	undoKey       = key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo theme"))
+	redoKey       = key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "redo theme"))
+)
+
+// actionKeys returns the bindings of the TUI's actions, in the order help
+// lists them
+func actionKeys() []key.Binding {
+	return []key.Binding{toggleKey, pairKey, compareKey, groupKey, addToGroupKey, referencesKey, contrastKey, fixKey, statsKey, windowKey, editKey, updateKey, viewKey, exportKey, undoKey, redoKey}
+}
+
+// listKeys returns the bindings of the list outside of filtering
+func listKeys(km list.KeyMap) []key.Binding {
+	return []key.Binding{km.CursorUp, km.CursorDown, km.PrevPage, km.NextPage, km.GoToStart, km.GoToEnd, km.Filter, km.ClearFilter, km.ShowFullHelp, km.CloseFullHelp, km.Quit, km.ForceQuit}
 }
 
 func initialModel() model {
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
-	l.Title = "Alacritheme"
+	l.Title = appTitle()
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
+	// b and u browse groups and undo, rather than paging back
+	l.KeyMap.PrevPage.SetKeys("left", "h", "pgup")
+	l.Filter = filterThemes
 	l.SetShowHelp(true)
 	l.Styles.Title = lipgloss.NewStyle().Bold(true).Background(lipgloss.NoColor{}).PaddingTop(1)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{toggleKey, compareKey, groupKey}
+	}
+
+	scripts, scriptsErr := loadedScripts()
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return append(actionKeys(), scripts.bindings()...)
+	}
+
+	input := textinput.New()
+	input.Prompt = "Add to group: "
+	input.Placeholder = "favorites"
+
+	themesDir, configFile, pathsErr := resolvePaths()
+	// resolvePaths already reports errors reading alacritheme's config
+	cfg, _, _ := loadAppConfig()
+	themesDirs, _ := resolveThemeDirs()
+	var filters []*scanFilter
+	var filterErr error
+	for _, dir := range themesDirs {
+		filter, err := newScanFilter(dir)
+		filters = append(filters, filter)
+		filterErr = errors.Join(filterErr, err)
+	}
+	pairs, err := loadPairs()
+	groups, groupsErr := loadGroups()
+	keys, keysErr := bindKeys(cfg.Keys)
+	if scriptsErr == nil {
+		scriptsErr = scripts.checkKeys(append(listKeys(l.KeyMap), actionKeys()...))
+	}
+	alacritty := newAlacrittyBackend(configFile, cfg)
+	backends := append([]backend{alacritty}, configuredBackends(cfg)...)
 
 	return model{
 		list:         l,
-		themesDir:    os.Getenv("THEMES_DIR"),
+		themesDir:    themesDir,
+		themesDirs:   themesDirs,
+		filters:      filters,
 		ready:        false,
-		configFile:   os.Getenv("CONFIG_FILE"),
-		tomlBackup:   make(map[string]interface{}),
+		alacritty:    alacritty,
+		backends:     backends,
 		lastSelected: -1,
+		pairs:        pairs,
+		groups:       groups,
+		groupInput:   input,
+		scripts:      scripts,
+		previews:     &previewTracker{},
+		locks:        cfg.Locks,
+		keys:         keys,
+		sortOrder:    cfg.Sort,
+		registries:   cfg.Registries,
+		minStars:     cfg.MinStars,
+		preview:      cfg.Preview,
+		compare:      cfg.Preview.Compare,
+		export:       cfg.Export,
+		err:          errors.Join(pathsErr, cfg.validate(), filterErr, err, groupsErr, keysErr, scriptsErr),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	// check if the config file exists
-	if _, err := os.Stat(m.configFile); os.IsNotExist(err) {
+	// check if the config file exists, a dry run doesn't create it
+	if _, err := os.Stat(m.alacritty.configFile); os.IsNotExist(err) && !dryRun {
 		// create the config file
-		if _, err := os.Create(m.configFile); err != nil {
+		if _, err := os.Create(m.alacritty.configFile); err != nil {
 			m.err = err
 			return nil
 		}
 	}
 
-	return loadFiles(m.themesDir)
+	if offline {
+		return m.scan()
+	}
+
+	return tea.Batch(m.scan(), func() tea.Msg {
+		outdated, err := checkUpdates()
+		return updatesCheckedMsg{outdated: outdated, err: err}
+	}, m.listRegistries())
 }
 
-func loadFiles(dir string) tea.Cmd {
-	return func() tea.Msg {
-		var items []list.Item
+// scanBatchSize is how many directory entries are read before the items
+// found so far are handed to the list
+const scanBatchSize = 32
+
+// scanRoot is a directory to list, along with the position, filter and
+// label of the themes directory it belongs to. builtin marks the built-in
+// themes, which are left out when a themes directory has one of the same name.
+type scanRoot struct {
+	dir     string
+	index   int
+	filter  *scanFilter
+	source  string
+	builtin bool
+}
 
-		files, err := os.ReadDir(dir)
-		if err != nil {
-			return filesLoadedMsg{nil, err}
-		}
-
-		// Add parent directory entry except for the initial themes directory
-		if dir != os.Getenv("THEMES_DIR") {
-			items = append(items, item{
-				title:       "..",
-				path:        filepath.Dir(dir),
-				isDirectory: true,
-			})
-		}
-
-		for _, file := range files {
-			filePath := filepath.Join(dir, file.Name())
-			if file.IsDir() || strings.HasSuffix(file.Name(), ".toml") {
-				items = append(items, item{
-					title:       file.Name(),
-					path:        filePath,
-					isDirectory: file.IsDir(),
-				})
-			}
-		}
+// dirScanner reads directories in batches so the list fills up while slow
+// directories (e.g. network mounts) are still being read
+type dirScanner struct {
+	scanRoot
+	// roots are all the directories to read, one after the other, and pos
+	// the position of the one being read
+	roots []scanRoot
+	pos   int
+	// flat reads the subdirectories of every root too, queued in pending,
+	// and titles themes by their path relative to the themes directory
+	flat    bool
+	pending []string
+	// current is the directory being read, dir or one of its subdirectories
+	current string
+	file    *os.File
+	// overlay is the matching directory in the overlay, whose edited copies
+	// replace the downloaded themes in current
+	overlay string
+	seen    map[string]bool
+	// listed are the file names of the themes listed so far, across roots
+	listed map[string]bool
+}
 
-		return filesLoadedMsg{items, nil}
+// scanRoots returns the themes directories to list, the primary one first
+func (m *model) scanRoots() []scanRoot {
+	// A single directory needs no source tags
+	labels := make([]string, len(m.themesDirs))
+	if len(m.themesDirs) > 1 {
+		labels = sourceLabels(m.themesDirs)
 	}
-}
 
-func (m *model) backupConfig() error {
-	content, err := os.ReadFile(m.configFile)
-	if err != nil {
-		return err
+	if m.browseDir != "" && !m.flat {
+		i := m.browseRoot
+		return []scanRoot{{dir: m.browseDir, index: i, filter: m.filters[i], source: labels[i]}}
 	}
 
-	var config map[string]interface{}
-	if err := toml.Unmarshal(content, &config); err != nil {
-		return err
+	roots := make([]scanRoot, len(m.themesDirs))
+	for i, dir := range m.themesDirs {
+		roots[i] = scanRoot{dir: dir, index: i, filter: m.filters[i], source: labels[i]}
+	}
+	if builtin := builtinRoot(); builtin != "" {
+		filter, _ := newScanFilter(builtin)
+		roots = append(roots, scanRoot{dir: builtin, index: len(m.themesDirs), filter: filter, source: builtinSource, builtin: true})
 	}
 
-	m.originalToml = content
-	m.tomlBackup = config
-	return err
+	return roots
 }
 
-func (m *model) updateConfig(selectedPath string) error {
-	content, err := os.ReadFile(m.configFile)
-	if err != nil {
-		return err
+// scan lists the themes directories, or the subdirectory being browsed
+func (m *model) scan() tea.Cmd {
+	return loadFiles(m.scanRoots(), m.flat)
+}
+
+func loadFiles(roots []scanRoot, flat bool) tea.Cmd {
+	scanner := &dirScanner{roots: roots, flat: flat, listed: make(map[string]bool)}
+
+	return func() tea.Msg {
+		if len(roots) == 0 {
+			return filesLoadedMsg{scanner: scanner, err: errors.New("no themes directory, set THEMES_DIR or themes_dir in alacritheme's config"), done: true}
+		}
+		return scanner.open(0)
 	}
+}
 
-	var config map[string]interface{}
-	if err := toml.Unmarshal(content, &config); err != nil {
-		return err
+// open starts reading the directory at pos in roots
+func (s *dirScanner) open(pos int) filesLoadedMsg {
+	s.pos, s.scanRoot = pos, s.roots[pos]
+	s.pending = nil
+
+	msg := s.openDir(s.dir)
+	// Add parent directory entry except for the initial themes directory
+	if !s.flat && s.dir != s.filter.root {
+		msg.items = append([]list.Item{s.item("..", filepath.Dir(s.dir), true)}, msg.items...)
+	}
+	// The colors pywal generated for the wallpaper head the list
+	if pos == 0 && s.dir == s.filter.root {
+		if wal, err := pywalFile(); err == nil && fileExists(wal) {
+			if theme, err := convertPywal(); err != nil {
+				trace("skipping %s: %v", wal, err)
+			} else {
+				i := s.item(pywalTitle, theme, false)
+				i.source = pywalTitle
+				msg.items = append([]list.Item{i}, msg.items...)
+			}
+		}
 	}
 
-	if config == nil {
-		config = make(map[string]interface{})
+	return msg
+}
+
+// openDir starts reading dir, the root's directory or one of its
+// subdirectories
+func (s *dirScanner) openDir(dir string) filesLoadedMsg {
+	s.current = dir
+	s.seen = make(map[string]bool)
+	s.overlay = ""
+	// Downloaded themes, and so the overlay, belong to the primary directory
+	if overlay := overlayPath(s.filter.root, dir); s.index == 0 && overlay != dir {
+		s.overlay = overlay
 	}
 
-	if general, ok := config["general"].(map[string]interface{}); ok {
-		general["live_config_reload"] = true
-		general["import"] = []string{selectedPath}
-	} else {
-		config["live_config_reload"] = true
-		config["import"] = []string{selectedPath}
+	file, err := os.Open(dir)
+	if err != nil {
+		return filesLoadedMsg{scanner: s, err: err, done: true}
 	}
+	s.file = file
 
-	var buf bytes.Buffer
-	encoder := toml.NewEncoder(&buf)
-	encoder.SetIndentTables(true)
-	if err := encoder.Encode(config); err != nil {
-		return err
+	return s.read()
+}
+
+// item returns a list item for an entry of the directory being read
+func (s *dirScanner) item(title, path string, isDirectory bool) item {
+	i := item{title: title, path: path, isDirectory: isDirectory, root: s.index, source: s.source}
+	if info, err := os.Stat(path); err == nil {
+		i.modTime = info.ModTime()
+	}
+	if !isDirectory {
+		// Keep the file contents around for full-text search
+		content, _ := os.ReadFile(path)
+		i.content = string(content)
+	}
+
+	return i
+}
+
+// title names a theme of the directory being read, by its path relative to
+// the themes directory in the flat view
+func (s *dirScanner) title(name string) string {
+	if !s.flat {
+		return name
 	}
 
-	return os.WriteFile(m.configFile, buf.Bytes(), 0644)
+	rel, err := filepath.Rel(s.filter.root, filepath.Join(s.current, name))
+	if err != nil {
+		return name
+	}
+	return filepath.ToSlash(rel)
 }
 
-func (m *model) restoreConfig() error {
-	return os.WriteFile(m.configFile, m.originalToml, 0644)
+// next returns a command reading the following batch of entries
+func (s *dirScanner) next() tea.Cmd {
+	return func() tea.Msg {
+		return s.read()
+	}
 }
 
-// Update the model's handleSelection method to pass viewport dimensions
-func (m *model) handleSelection() tea.Cmd {
-	currentIndex := m.list.Index()
+func (s *dirScanner) read() filesLoadedMsg {
+	files, err := s.file.ReadDir(scanBatchSize)
+	if err == io.EOF {
+		s.file.Close()
+		items := s.overlayOnly()
+
+		var msg filesLoadedMsg
+		switch {
+		case len(s.pending) > 0:
+			dir := s.pending[0]
+			s.pending = s.pending[1:]
+			msg = s.openDir(dir)
+		case s.pos+1 < len(s.roots):
+			msg = s.open(s.pos + 1)
+		default:
+			return filesLoadedMsg{scanner: s, items: items, done: true}
+		}
 
-	if m.lastSelected == currentIndex && m.lastSelected != -1 {
-		return nil
+		msg.items = append(items, msg.items...)
+		return msg
+	} else if err != nil {
+		s.file.Close()
+		return filesLoadedMsg{scanner: s, err: err, done: true}
 	}
 
-	m.lastSelected = currentIndex
-	if i, ok := m.list.SelectedItem().(item); ok {
-		if !i.isDirectory && strings.HasSuffix(i.path, ".toml") {
-			content, err := os.ReadFile(i.path)
+	var items []list.Item
+	for _, file := range files {
+		filePath := filepath.Join(s.current, file.Name())
+		if s.filter.ignored(filePath, file.IsDir()) {
+			continue
+		}
+
+		if file.IsDir() && s.flat {
+			s.pending = append(s.pending, filePath)
+		} else if file.IsDir() {
+			items = append(items, s.item(file.Name(), filePath, true))
+		} else if strings.HasSuffix(file.Name(), ".toml") && !strings.HasSuffix(file.Name(), templateSuffix) {
+			if s.builtin && s.listed[file.Name()] {
+				continue
+			}
+			s.seen[file.Name()] = true
+			s.listed[file.Name()] = true
+			if s.overlay != "" && fileExists(filepath.Join(s.overlay, file.Name())) {
+				filePath = filepath.Join(s.overlay, file.Name())
+			}
+			items = append(items, s.item(s.title(file.Name()), filePath, false))
+		} else if isSchemeFile(file.Name()) {
+			converted, err := convertScheme(filePath)
 			if err != nil {
-				m.err = err
-				return nil
+				// Not every YAML file is a scheme
+				trace("skipping %s: %v", filePath, err)
+				continue
 			}
+			items = append(items, s.item(s.title(file.Name()), converted, false))
+		}
+	}
 
-			// Pass viewport dimensions to renderColorPreview
-			preview := renderColorPreview(string(content), m.viewport.Width)
-			m.viewport.SetContent(preview)
+	return filesLoadedMsg{scanner: s, items: items}
+}
 
-			return func() tea.Msg {
-				if err := m.updateConfig(i.path); err != nil {
-					return themeSelectedMsg{path: i.path, err: err}
-				}
-				return themeSelectedMsg{path: i.path, err: nil}
-			}
+// overlayOnly returns the themes that only exist in the overlay, such as
+// variants of downloaded themes
+func (s *dirScanner) overlayOnly() []list.Item {
+	if s.overlay == "" {
+		return nil
+	}
+
+	files, _ := os.ReadDir(s.overlay)
+	var items []list.Item
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || s.seen[name] || !strings.HasSuffix(name, ".toml") || strings.HasSuffix(name, templateSuffix) {
+			continue
+		}
+		if s.filter.ignored(filepath.Join(s.current, name), false) {
+			continue
 		}
+
+		s.listed[name] = true
+		items = append(items, s.item(s.title(name), filepath.Join(s.overlay, name), false))
+	}
+
+	return items
+}
+
+// backupConfig backs up Alacritty's config before anything is previewed, and
+// compares themes with the one it imports
+func (m *model) backupConfig() error {
+	if err := m.alacritty.backup(); err != nil {
+		return err
 	}
 
+	m.currentTheme = m.alacritty.theme
+	if m.currentTheme != "" {
+		// Undo goes back as far as the theme there was to begin with
+		m.record(m.currentTheme)
+	}
 	return nil
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmds []tea.Cmd
+// importedTheme returns the theme import of the config at configFile, the
+// last import that's a color theme, which is the one alacritheme manages, or
+// an empty string if there is none. In the include mode, it's the theme the
+// include file imports.
+func importedTheme(config map[string]interface{}, configFile string) string {
+	imports := configImports(config)
+	if i := themeImport(imports, configFile); i >= 0 {
+		return imports[i]
+	}
 
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.windowSize = msg
-		if !m.ready {
-			m.viewport = viewport.New(msg.Width/2, msg.Height)
-			m.list.SetWidth(msg.Width / 2)
-			m.list.SetHeight(msg.Height)
-			m.ready = true
+	include := includeFile(configFile)
+	for _, path := range imports {
+		if resolveImport(path, configFile) != include {
+			continue
+		}
+		content, err := os.ReadFile(include)
+		if err != nil {
+			return ""
+		}
+		var included map[string]interface{}
+		if err := toml.Unmarshal(content, &included); err != nil {
+			return ""
 		}
+		imports := configImports(included)
+		if i := themeImport(imports, include); i >= 0 {
+			return resolveImport(imports[i], include)
+		}
+	}
 
-	case filesLoadedMsg:
-		if msg.err != nil {
-			m.err = msg.err
-			return m, nil
+	return ""
+}
+
+// themeImport returns the index of the last of imports, with ~ expanded, that
+// is a color theme, or -1. Other imports, e.g. fonts or key bindings split off
+// the config, are left alone.
+func themeImport(imports []string, configFile string) int {
+	for i := len(imports) - 1; i >= 0; i-- {
+		if isThemeFile(resolveImport(imports[i], configFile)) {
+			return i
 		}
+	}
 
-		// Set the full list of items (unfiltered)
-		m.items = msg.items
-		m.list.SetItems(m.items)
+	return -1
+}
 
-		// Handle initial selection for the first item
-		cmds = append(cmds, m.handleSelection())
+// resolveImport returns the absolute path of an import of the config at
+// configFile, resolving relative imports against its directory like
+// Alacritty does
+func resolveImport(path, configFile string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configFile), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
 
-	case tea.KeyMsg:
-		switch msg.String() {
-		case tea.KeyCtrlC.String(), "q":
-			if err := m.restoreConfig(); err != nil {
-				m.err = err
-			}
-			return m, tea.Quit
-		case tea.KeyEnter.String():
-			newList, cmd := m.list.Update(msg)
-			m.list = newList
-			cmds = append(cmds, cmd)
+	return path
+}
 
-			cmds = append(cmds, m.handleSelection())
-			return m, tea.Quit
-		case tea.KeyUp.String(), tea.KeyDown.String(), "k", "j":
-			newList, cmd := m.list.Update(msg)
-			m.list = newList
-			cmds = append(cmds, cmd)
+// isThemeFile reports whether the file at path holds nothing but colors
+func isThemeFile(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		return false
+	}
 
-			cmds = append(cmds, m.handleSelection())
-		case tea.KeyRight.String(), tea.KeyPgDown.String(), "l":
-			m.list.NextPage()
-			cmds = append(cmds, m.handleSelection())
-		case tea.KeyLeft.String(), tea.KeyPgUp.String(), "h":
-			m.list.PrevPage()
-			cmds = append(cmds, m.handleSelection())
-		case "/": // Add explicit filter trigger
-			m.list.ShowFilter()
-			return m, nil
-		default:
-			newList, cmd := m.list.Update(msg)
-			m.list = newList
-			cmds = append(cmds, cmd)
+	_, ok := theme["colors"]
+	return ok && len(theme) == 1
+}
+
+// configImports returns the import paths of a config, read from [general]
+// when present and from the top level otherwise, with ~ expanded
+func configImports(config map[string]interface{}) []string {
+	imports, _ := config["import"].([]interface{})
+	if general, ok := config["general"].(map[string]interface{}); ok {
+		if generalImports, found := general["import"].([]interface{}); found {
+			imports = generalImports
 		}
 	}
 
-	// Handle viewport updates
-	newViewport, cmd := m.viewport.Update(msg)
-	m.viewport = newViewport
-	if cmd != nil {
-		cmds = append(cmds, cmd)
+	paths := make([]string, 0, len(imports))
+	for _, imp := range imports {
+		if path, ok := imp.(string); ok {
+			paths = append(paths, expandHome(path))
+		}
 	}
 
-	return m, tea.Batch(cmds...)
+	return paths
 }
 
-func (m model) View() string {
-	if !m.ready {
-		return "Initializing..."
+// expandHome replaces a leading ~ in path with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
 	}
 
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v", m.err)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
 	}
 
-	return lipgloss.JoinHorizontal(
-		lipgloss.Left,
-		m.list.View(),
-		m.viewport.View(),
-	)
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
 }
 
-func main() {
-	m := initialModel()
-	if err := m.backupConfig(); err != nil {
-		fmt.Printf("error: couldn't backup config")
-		os.Exit(1)
+// appTitle is the list title, which tells when nothing is written
+func appTitle() string {
+	if dryRun {
+		return "Alacritheme (dry run)"
 	}
 
-	p := tea.NewProgram(
-		m,
-		tea.WithAltScreen(),
-	)
+	return "Alacritheme"
+}
+
+// renderPreview starts rendering the theme at path in the background, side
+// by side with the currently applied theme when compare mode is on
+func (m *model) renderPreview(path string) tea.Cmd {
+	m.previewSeq++
+	m.viewport.SetContent(lipgloss.NewStyle().Padding(1, 2).Faint(true).Render("Rendering preview…"))
+
+	seq, width, compare, currentTheme, locks, opts := m.previewSeq, m.viewport.Width, m.compare, m.currentTheme, m.locks, m.preview
+	return func() tea.Msg {
+		preview, err := buildPreview(path, width, compare, currentTheme, locks, opts)
+		return previewReadyMsg{seq: seq, content: preview, err: err}
+	}
+}
+
+func buildPreview(path string, width int, compare bool, currentTheme string, locks map[string]string, opts previewConfig) (string, error) {
+	content, err := readLocked(path, locks)
+	if err != nil {
+		return "", err
+	}
 
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running program: %v", err)
-		os.Exit(1)
+	if !compare {
+		// Pass viewport dimensions to renderColorPreview
+		return renderColorPreview(string(content), width, opts), nil
+	}
+
+	current := "No theme currently applied"
+	if currentTheme != "" {
+		currentContent, err := readLocked(currentTheme, locks)
+		if err != nil {
+			return "", err
+		}
+		current = renderColorPreview(string(currentContent), width/2, opts)
+	}
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Width(width / 2).
+		Align(lipgloss.Center)
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		lipgloss.JoinVertical(lipgloss.Center, headerStyle.Render("Current: "+filepath.Base(currentTheme)), current),
+		lipgloss.JoinVertical(lipgloss.Center, headerStyle.Render("Selected: "+filepath.Base(path)), renderColorPreview(string(content), width/2, opts)),
+	), nil
+}
+
+// toggleCompare switches the preview between the selected theme alone and a
+// split against the currently applied theme
+func (m *model) toggleCompare() tea.Cmd {
+	m.compare = !m.compare
+
+	if i, ok := m.list.SelectedItem().(item); ok && !i.isDirectory {
+		return m.renderPreview(i.path)
+	}
+
+	return nil
+}
+
+// Update the model's handleSelection method to pass viewport dimensions
+func (m *model) handleSelection() tea.Cmd {
+	currentIndex := m.list.Index()
+
+	if m.lastSelected == currentIndex && m.lastSelected != -1 {
+		return nil
+	}
+
+	if m.list.SelectedItem() == nil {
+		return nil
+	}
+
+	m.lastSelected = currentIndex
+	if i, ok := m.list.SelectedItem().(item); ok && i.remote != nil {
+		// Download once the cursor rests on the theme
+		m.applySeq++
+		seq, theme := m.applySeq, *i.remote
+		return tea.Tick(settleDelay, func(time.Time) tea.Msg {
+			return registrySettledMsg{seq: seq, theme: theme}
+		})
+	}
+	if i, ok := m.list.SelectedItem().(item); ok {
+		if !i.isDirectory && strings.HasSuffix(i.path, ".toml") {
+			preview := m.renderPreview(i.path)
+			m.previewed = i.path
+
+			// Only write the config once the cursor rests on a theme
+			m.applySeq++
+			seq, path := m.applySeq, i.path
+			return tea.Batch(preview, tea.Tick(settleDelay, func(time.Time) tea.Msg {
+				return selectionSettledMsg{seq: seq, path: path}
+			}))
+		}
+	}
+
+	return nil
+}
+
+// applySelection previews the settled selection through the backends in the
+// background, e.g. writing Alacritty's config so it reloads it
+func (m *model) applySelection(path string) tea.Cmd {
+	backends, previews := m.backends, m.previews
+	return func() tea.Msg {
+		if !previews.start() {
+			return nil
+		}
+		defer previews.done()
+
+		if err := previewAll(backends, path); err != nil {
+			return themeSelectedMsg{path: path, err: err}
+		}
+		if err := m.scripts.emit(themePreviewedEvent, path); err != nil {
+			return themeSelectedMsg{path: path, err: err}
+		}
+		return themeSelectedMsg{path: path, err: nil}
+	}
+}
+
+// previewTracker counts the previews written in the background, which
+// applying and restoring wait for, so none lands on the config after them
+type previewTracker struct {
+	mu      sync.Mutex
+	running sync.WaitGroup
+	stopped bool
+}
+
+// start reports whether a preview may still be written, and counts it until
+// done
+func (t *previewTracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.running.Add(1)
+	return true
+}
+
+func (t *previewTracker) done() {
+	t.running.Done()
+}
+
+// stop lets no more previews start and waits for the running ones
+func (t *previewTracker) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+	t.running.Wait()
+}
+
+// record adds a previewed theme to the history, dropping the themes undone
+// before it
+func (m *model) record(path string) {
+	if len(m.history) > 0 && m.history[m.historyPos] == path {
+		return
+	}
+	if len(m.history) > 0 {
+		m.history = m.history[:m.historyPos+1]
+	}
+
+	m.history = append(m.history, path)
+	m.historyPos = len(m.history) - 1
+}
+
+// stepHistory previews the theme steps back in the history, or forward for a
+// positive steps, selecting it in the list
+func (m *model) stepHistory(steps int) tea.Cmd {
+	pos := m.historyPos + steps
+	if pos < 0 || pos >= len(m.history) {
+		if steps < 0 {
+			return m.list.NewStatusMessage("Nothing to undo")
+		}
+		return m.list.NewStatusMessage("Nothing to redo")
+	}
+	m.historyPos = pos
+	path := m.history[pos]
+
+	m.lastSelected = -1
+	for idx, it := range m.items {
+		if it.(item).path == path {
+			// idx points into the unfiltered items
+			m.list.ResetFilter()
+			m.list.Select(idx)
+			return m.handleSelection()
+		}
+	}
+
+	// The theme isn't listed in the directory or group being browsed
+	m.previewed = path
+	m.applySeq++
+	return tea.Batch(m.renderPreview(path), m.applySelection(path))
+}
+
+// counterpart finds the list index of the light/dark counterpart of i,
+// preferring a manually linked pair over one guessed from the name
+func (m *model) counterpart(i item) (int, bool) {
+	if path, ok := m.pairs[i.path]; ok {
+		for idx, it := range m.items {
+			if it.(item).path == path {
+				return idx, true
+			}
+		}
+	}
+
+	// Prefer the counterpart from the same themes directory
+	for _, name := range counterpartNames(i.title) {
+		found := -1
+		for idx, it := range m.items {
+			if c := it.(item); c.title == name {
+				if c.root == i.root {
+					return idx, true
+				} else if found == -1 {
+					found = idx
+				}
+			}
+		}
+		if found != -1 {
+			return found, true
+		}
+	}
+
+	return -1, false
+}
+
+// togglePair flips the selected theme to its light/dark counterpart
+func (m *model) togglePair() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	idx, ok := m.counterpart(i)
+	if !ok {
+		return m.list.NewStatusMessage("No light/dark counterpart for " + i.title)
+	}
+
+	// idx points into the unfiltered items
+	m.list.ResetFilter()
+	m.list.Select(idx)
+	return m.handleSelection()
+}
+
+// markPair links two themes as a light/dark pair: the first press marks the
+// selected theme, the second links it with the theme selected at that point
+func (m *model) markPair() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	if m.pairMark == "" || m.pairMark == i.path {
+		m.pairMark = i.path
+		return m.list.NewStatusMessage("Marked " + i.title + ", select its counterpart and press p again")
+	}
+
+	linkPair(m.pairs, m.pairMark, i.path)
+	mark := filepath.Base(m.pairMark)
+	m.pairMark = ""
+	if err := savePairs(m.pairs); err != nil {
+		m.err = err
+		return nil
+	}
+
+	return m.list.NewStatusMessage("Paired " + mark + " with " + i.title)
+}
+
+// addItems merges a batch of scanned items into the list, keeping it sorted
+// by name and the cursor on the item it was on
+func (m *model) addItems(items []list.Item) tea.Cmd {
+	selected, hadSelection := m.list.SelectedItem().(item)
+
+	for idx, it := range items {
+		i := it.(item)
+		i.outdated = m.isOutdated(i.path)
+		items[idx] = i
+	}
+
+	// Themes are listed per themes directory, in the configured order
+	m.items = append(m.items, items...)
+	sort.SliceStable(m.items, func(a, b int) bool {
+		ia, ib := m.items[a].(item), m.items[b].(item)
+		if ia.title == ".." || ib.title == ".." {
+			return ia.title == ".."
+		}
+		if ia.root != ib.root {
+			return ia.root < ib.root
+		}
+		if m.sortOrder == sortByModified && !ia.modTime.Equal(ib.modTime) {
+			return ia.modTime.After(ib.modTime)
+		}
+		if m.sortOrder == sortByPopularity && ia.stars() != ib.stars() {
+			return ia.stars() > ib.stars()
+		}
+		return ia.title < ib.title
+	})
+	m.markDuplicates()
+	cmd := m.list.SetItems(m.items)
+
+	if hadSelection && len(items) > 0 {
+		for idx, it := range m.list.VisibleItems() {
+			// Titles are unique within a directory, and match when the
+			// selected theme was replaced by its edited copy
+			i := it.(item)
+			if i.path == selected.path || (!selected.isDirectory && i.title == selected.title && i.root == selected.root) {
+				m.list.Select(idx)
+				if m.lastSelected != -1 {
+					m.lastSelected = idx
+				}
+				break
+			}
+		}
+	}
+
+	return cmd
+}
+
+// markDuplicates flags the themes whose name is also used in another themes
+// directory, so their titles show where they come from
+func (m *model) markDuplicates() {
+	count := make(map[string]int)
+	for _, it := range m.items {
+		if i := it.(item); !i.isDirectory {
+			count[i.title]++
+		}
+	}
+
+	for idx, it := range m.items {
+		i := it.(item)
+		i.duplicate = !i.isDirectory && count[i.title] > 1
+		m.items[idx] = i
+	}
+}
+
+// isOutdated reports whether the theme at path has changed upstream
+func (m *model) isOutdated(path string) bool {
+	abs, err := filepath.Abs(path)
+	return err == nil && m.outdated[abs]
+}
+
+// markOutdated flags the listed themes that have changed upstream
+func (m *model) markOutdated() tea.Cmd {
+	for idx, it := range m.items {
+		i := it.(item)
+		i.outdated = m.isOutdated(i.path)
+		m.items[idx] = i
+	}
+
+	return m.list.SetItems(m.items)
+}
+
+// updateSelected downloads the upstream version of the selected theme
+func (m *model) updateSelected() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+	if !i.outdated {
+		return m.list.NewStatusMessage(i.title + " is up to date")
+	}
+
+	return func() tea.Msg {
+		return themeUpdatedMsg{path: i.path, err: updateTheme(i.path)}
+	}
+}
+
+// showReferences replaces the preview with the list of configs importing the
+// selected theme
+func (m *model) showReferences() {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return
+	}
+
+	configFile, _ := filepath.Abs(m.alacritty.configFile)
+	refs := findReferences(i.path, knownConfigs(configFile), map[string][]byte{
+		configFile: m.alacritty.original,
+	})
+
+	m.viewport.SetContent(lipgloss.NewStyle().Padding(1, 2).Render(renderReferences(i.path, refs)))
+}
+
+// runScriptAction runs a script-defined action on the selected theme and
+// shows the text it returns in the status bar
+func (m *model) runScriptAction(action scriptAction) tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	status, err := m.scripts.run(action, i.path)
+	if err != nil {
+		return m.list.NewStatusMessage("Error: " + err.Error())
+	}
+	if status == "" {
+		return nil
+	}
+
+	return m.list.NewStatusMessage(status)
+}
+
+// showContrast replaces the preview with the contrast report of the
+// selected theme
+func (m *model) showContrast() {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return
+	}
+
+	scheme, err := readScheme(i.path)
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	issues, err := contrastIssues(scheme)
+	if err != nil {
+		m.viewport.SetContent(lipgloss.NewStyle().Padding(1, 2).Render(err.Error()))
+		return
+	}
+
+	m.viewport.SetContent(lipgloss.NewStyle().Padding(1, 2).Render(renderContrastReport(i.title, issues)))
+}
+
+// showStats replaces the preview with the palette statistics of the
+// selected theme
+func (m *model) showStats() {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return
+	}
+
+	scheme, err := readScheme(i.path)
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	m.viewport.SetContent(lipgloss.NewStyle().Padding(1, 2).Render(renderStats(i.title, computeStats(scheme))))
+}
+
+// editSelected opens the selected theme in an editor. Downloaded themes are
+// kept pristine, their copy in the overlay is edited instead.
+func (m *model) editSelected() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	path, err := editableTheme(m.themesDir, i.path)
+	if err != nil {
+		return m.list.NewStatusMessage("Error: " + err.Error())
+	}
+
+	return editTheme(path)
+}
+
+// saveContrastFix writes a variant of the selected theme with the suggested
+// contrast fixes applied next to the original
+func (m *model) saveContrastFix() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	scheme, err := readScheme(i.path)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+
+	issues, err := contrastIssues(scheme)
+	if err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	if len(issues) == 0 {
+		return m.list.NewStatusMessage(i.title + " has no contrast issues to fix")
+	}
+
+	base, err := variantBase(m.themesDir, i.path)
+	if err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	variant, err := saveFixedVariant(i.path, base, issues)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+
+	status := m.list.NewStatusMessage("Saved " + filepath.Base(variant))
+	if m.group != "" {
+		return status
+	}
+
+	return tea.Batch(status, m.scan())
+}
+
+// exportSelected converts the selected theme to the configured export
+// format and writes it to the export directory
+func (m *model) exportSelected() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	name := m.export.format()
+	format, err := lookupFormat(name)
+	if err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+	dir, err := exportDir(m.export, name, format)
+	if err != nil {
+		return m.list.NewStatusMessage(err.Error())
+	}
+
+	scheme, err := readScheme(i.path)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	content, err := format.write(schemeName(i.path), scheme)
+	if err != nil {
+		return m.list.NewStatusMessage(fmt.Sprintf("%s: %v", i.title, err))
+	}
+
+	theme := strings.TrimSuffix(filepath.Base(i.path), ".toml")
+	if source := convertedSource(i.path); source != "" {
+		theme = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+	target := filepath.Join(dir, theme+format.ext)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		m.err = err
+		return nil
+	}
+	trace("writing %s", target)
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		m.err = err
+		return nil
+	}
+
+	return m.list.NewStatusMessage("Exported " + target)
+}
+
+// browse lists the directory of the selected item, where ".." goes back up
+func (m *model) browse(dir item) tea.Cmd {
+	m.browseDir, m.browseRoot = dir.path, dir.root
+	if dir.root >= len(m.themesDirs) || dir.path == m.themesDirs[dir.root] {
+		m.browseDir = ""
+	}
+	m.lastSelected = -1
+	m.list.ResetSelected()
+
+	return m.scan()
+}
+
+// toggleFlat switches between browsing one directory at a time and listing
+// the themes of every subdirectory by their relative path
+func (m *model) toggleFlat() tea.Cmd {
+	if m.group != "" {
+		return m.list.NewStatusMessage("Groups are always flat, press b to go back to all themes")
+	}
+
+	m.flat = !m.flat
+	m.lastSelected = -1
+	status := "Listing the themes of every subdirectory"
+	if !m.flat {
+		status = "Browsing one directory at a time"
+	}
+
+	return tea.Batch(m.list.NewStatusMessage(status), m.scan())
+}
+
+// listRegistries lists the themes of the configured registries in the
+// background
+func (m *model) listRegistries() tea.Cmd {
+	if len(m.registries) == 0 {
+		return nil
+	}
+
+	registries := m.registries
+	return func() tea.Msg {
+		themes, err := listRegistries(registries)
+		return registriesListedMsg{themes: themes, err: err}
+	}
+}
+
+// remoteItems returns the registry themes that haven't been downloaded yet,
+// listed after the local themes of the top directory or the flat view
+func (m *model) remoteItems() []list.Item {
+	if m.group != "" || (m.browseDir != "" && !m.flat) || len(m.themesDirs) == 0 {
+		return nil
+	}
+
+	var items []list.Item
+	for idx, t := range m.registryThemes {
+		if t.stars < m.minStars || fileExists(filepath.Join(registryDir(m.themesDirs[0], t.registry), t.name)) {
+			continue
+		}
+		items = append(items, item{
+			title:  t.name,
+			path:   t.url,
+			root:   len(m.themesDirs) + 1,
+			source: "remote: " + t.registry,
+			remote: &m.registryThemes[idx],
+		})
+	}
+
+	return items
+}
+
+// downloadRemote downloads a registry theme into the primary themes directory
+func (m *model) downloadRemote(t registryTheme) tea.Cmd {
+	themesDir := m.themesDirs[0]
+	return tea.Batch(m.list.NewStatusMessage("Downloading "+t.name+"…"), func() tea.Msg {
+		path, err := downloadRegistryTheme(t, themesDir)
+		return registryDownloadedMsg{theme: t, path: path, err: err}
+	})
+}
+
+// replaceRemote turns the listed registry theme into the theme downloaded
+// to path
+func (m *model) replaceRemote(t registryTheme, path string) tea.Cmd {
+	content, _ := os.ReadFile(path)
+	title := t.name
+	if m.flat {
+		title = t.registry + "/" + t.name
+	}
+
+	for idx, it := range m.items {
+		if i := it.(item); i.remote != nil && *i.remote == t {
+			m.items[idx] = item{title: title, path: path, content: string(content), root: i.root, source: i.source, modTime: time.Now()}
+		}
+	}
+
+	return m.list.SetItems(m.items)
+}
+
+// nextGroup cycles the list through all themes and each named group
+func (m *model) nextGroup() tea.Cmd {
+	names := groupNames(m.groups)
+	if len(names) == 0 {
+		return m.list.NewStatusMessage("No groups yet, press a to add a theme to one")
+	}
+
+	next := ""
+	if m.group == "" {
+		next = names[0]
+	}
+	for i, name := range names {
+		if name == m.group && i+1 < len(names) {
+			next = names[i+1]
+		}
+	}
+
+	return m.showGroup(next)
+}
+
+// showGroup lists the themes of the named group, or the themes directory if
+// name is empty
+func (m *model) showGroup(name string) tea.Cmd {
+	m.group = name
+	m.lastSelected = -1
+
+	if name == "" {
+		m.list.Title = appTitle()
+		return m.scan()
+	}
+
+	m.list.Title = appTitle() + " · " + name
+	m.items = groupItems(m.groups[name])
+	m.list.ResetSelected()
+	cmd := m.list.SetItems(m.items)
+
+	return tea.Batch(cmd, m.handleSelection())
+}
+
+// addToGroup toggles the selected theme's membership of the group being
+// browsed, or asks for a group name when browsing all themes
+func (m *model) addToGroup() tea.Cmd {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || !i.isLocalTheme() {
+		return nil
+	}
+
+	if m.group == "" {
+		m.prompting = true
+		m.groupInput.Reset()
+		m.list.Title = m.groupInput.View()
+		return m.groupInput.Focus()
+	}
+
+	group := m.group
+	toggleMember(m.groups, group, i.path)
+	if err := saveGroups(m.groups); err != nil {
+		m.err = err
+		return nil
+	}
+
+	if _, ok := m.groups[group]; !ok {
+		return tea.Batch(m.showGroup(""), m.list.NewStatusMessage("Group "+group+" is now empty"))
+	}
+
+	return tea.Batch(m.showGroup(group), m.list.NewStatusMessage("Removed "+i.title+" from "+group))
+}
+
+// updateGroupInput handles key presses while the group name prompt is open
+func (m *model) updateGroupInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case tea.KeyEsc.String(), tea.KeyCtrlC.String():
+		m.prompting = false
+		m.list.Title = appTitle()
+		return nil
+	case tea.KeyEnter.String():
+		m.prompting = false
+		m.list.Title = appTitle()
+
+		name := strings.TrimSpace(m.groupInput.Value())
+		i, ok := m.list.SelectedItem().(item)
+		if name == "" || !ok {
+			return nil
+		}
+
+		status := "Added " + i.title + " to " + name
+		if !toggleMember(m.groups, name, i.path) {
+			status = "Removed " + i.title + " from " + name
+		}
+		if err := saveGroups(m.groups); err != nil {
+			m.err = err
+			return nil
+		}
+
+		return m.list.NewStatusMessage(status)
+	}
+
+	var cmd tea.Cmd
+	m.groupInput, cmd = m.groupInput.Update(msg)
+	m.list.Title = m.groupInput.View()
+	return cmd
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.windowSize = msg
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width/2, msg.Height)
+			m.list.SetWidth(msg.Width / 2)
+			m.list.SetHeight(msg.Height)
+			m.ready = true
+		}
+
+	case previewReadyMsg:
+		// Only the preview of the latest selection is shown
+		if msg.seq != m.previewSeq {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.viewport.SetContent(msg.content)
+
+	case filesLoadedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+
+		// Drop batches from a scan that has since been replaced
+		first := m.scanner != msg.scanner
+		if first && m.group != "" {
+			return m, nil
+		}
+		if first {
+			m.scanner = msg.scanner
+			m.items = nil
+			m.lastSelected = -1
+		}
+
+		items := msg.items
+		m.scanDone = msg.done
+		if msg.done {
+			items = append(items, m.remoteItems()...)
+		}
+		cmds = append(cmds, m.addItems(items))
+		if !msg.done {
+			cmds = append(cmds, msg.scanner.next())
+		}
+
+		// Handle initial selection once the first item shows up
+		if m.lastSelected == -1 {
+			cmds = append(cmds, m.handleSelection())
+		}
+
+	case tea.KeyMsg:
+		if m.prompting {
+			return m, m.updateGroupInput(msg)
+		}
+
+		// While the filter is being typed, every key belongs to the filter input
+		if m.list.FilterState() == list.Filtering {
+			newList, cmd := m.list.Update(msg)
+			m.list = newList
+			cmds = append(cmds, cmd)
+
+			cmds = append(cmds, m.handleSelection())
+			return m, tea.Batch(cmds...)
+		}
+
+		switch m.keys.translate(msg.String()) {
+		case tea.KeyCtrlC.String(), "q":
+			m.previews.stop()
+			if err := restoreAll(m.backends); err != nil {
+				m.err = err
+			}
+			m.restored = m.err == nil
+			m.quit = true
+			return m, tea.Quit
+		case tea.KeyEnter.String():
+			if i, ok := m.list.SelectedItem().(item); ok && i.isDirectory {
+				cmds = append(cmds, m.browse(i))
+				break
+			}
+
+			newList, cmd := m.list.Update(msg)
+			m.list = newList
+			cmds = append(cmds, cmd)
+
+			cmds = append(cmds, m.handleSelection())
+			m.previews.stop()
+			if m.previewed != "" {
+				if err := applyAll(m.backends, m.previewed); err != nil {
+					m.err = err
+				}
+			}
+			if m.previewed != "" && m.err == nil && !dryRun {
+				if err := m.scripts.emit(themeAppliedEvent, m.previewed); err != nil {
+					m.err = err
+				}
+			}
+			m.quit = true
+			return m, tea.Quit
+		case tea.KeyUp.String(), tea.KeyDown.String(), "k", "j":
+			newList, cmd := m.list.Update(msg)
+			m.list = newList
+			cmds = append(cmds, cmd)
+
+			cmds = append(cmds, m.handleSelection())
+		case tea.KeyRight.String(), tea.KeyPgDown.String(), "l":
+			m.list.NextPage()
+			cmds = append(cmds, m.handleSelection())
+		case tea.KeyLeft.String(), tea.KeyPgUp.String(), "h":
+			m.list.PrevPage()
+			cmds = append(cmds, m.handleSelection())
+		case "t":
+			cmds = append(cmds, m.togglePair())
+		case "p":
+			cmds = append(cmds, m.markPair())
+		case "c":
+			cmds = append(cmds, m.toggleCompare())
+		case "b":
+			cmds = append(cmds, m.nextGroup())
+		case "a":
+			cmds = append(cmds, m.addToGroup())
+		case "r":
+			m.showReferences()
+		case "w":
+			m.showContrast()
+		case "F":
+			cmds = append(cmds, m.saveContrastFix())
+		case "s":
+			m.showStats()
+		case "e":
+			cmds = append(cmds, m.editSelected())
+		case "U":
+			cmds = append(cmds, m.updateSelected())
+		case "v":
+			cmds = append(cmds, m.toggleFlat())
+		case "x":
+			cmds = append(cmds, m.exportSelected())
+		case "u":
+			cmds = append(cmds, m.stepHistory(-1))
+		case "ctrl+r":
+			cmds = append(cmds, m.stepHistory(1))
+		case "n":
+			if i, ok := m.list.SelectedItem().(item); ok && !i.isDirectory {
+				cmds = append(cmds, openWindow(m.alacritty.original, m.alacritty.configFile, i.path, m.alacritty.opts))
+			}
+		case "/": // Add explicit filter trigger
+			newList, cmd := m.list.Update(msg)
+			m.list = newList
+			return m, cmd
+		default:
+			if action, ok := m.scripts.action(msg.String()); ok {
+				cmds = append(cmds, m.runScriptAction(action))
+				break
+			}
+
+			newList, cmd := m.list.Update(msg)
+			m.list = newList
+			cmds = append(cmds, cmd)
+		}
+
+	case selectionSettledMsg:
+		if msg.seq == m.applySeq {
+			m.record(msg.path)
+			cmds = append(cmds, m.applySelection(msg.path))
+		}
+
+	case themeSelectedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+		}
+
+	case registriesListedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+		}
+		m.registryThemes = msg.themes
+		if m.scanDone {
+			cmds = append(cmds, m.addItems(m.remoteItems()))
+		}
+
+	case registrySettledMsg:
+		if msg.seq == m.applySeq {
+			cmds = append(cmds, m.downloadRemote(msg.theme))
+		}
+
+	case registryDownloadedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+			break
+		}
+
+		m.lastSelected = -1
+		cmds = append(cmds, m.replaceRemote(msg.theme, msg.path), m.handleSelection(), m.list.NewStatusMessage("Downloaded "+msg.path))
+
+	case updatesCheckedMsg:
+		// Checking is best effort, the list works the same without it
+		if msg.err == nil {
+			m.outdated = msg.outdated
+			cmds = append(cmds, m.markOutdated())
+		}
+
+	case themeUpdatedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+			break
+		}
+
+		if abs, err := filepath.Abs(msg.path); err == nil {
+			delete(m.outdated, abs)
+		}
+		m.lastSelected = -1
+		cmds = append(cmds, m.markOutdated(), m.handleSelection(), m.list.NewStatusMessage("Updated "+filepath.Base(msg.path)))
+
+	case themeEditedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+			break
+		}
+
+		// Reload, so the list, preview and config pick up the edited file
+		m.lastSelected = -1
+		cmds = append(cmds, m.list.NewStatusMessage("Saved "+msg.path))
+		if m.group == "" && m.scanner != nil {
+			cmds = append(cmds, m.scan())
+		} else {
+			cmds = append(cmds, m.handleSelection())
+		}
+
+	case windowOpenedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, m.list.NewStatusMessage("Error: "+msg.err.Error()))
+		} else {
+			cmds = append(cmds, m.list.NewStatusMessage("Opened "+filepath.Base(msg.path)+" in a new window"))
+		}
+
+	default:
+		// Filter results and status message timeouts arrive as their own messages
+		newList, cmd := m.list.Update(msg)
+		m.list = newList
+		cmds = append(cmds, cmd)
+	}
+
+	// Handle viewport updates
+	newViewport, cmd := m.viewport.Update(msg)
+	m.viewport = newViewport
+	if cmd != nil {
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+func (m model) View() string {
+	if !m.ready {
+		return "Initializing..."
+	}
+
+	if m.err != nil {
+		return fmt.Sprintf("Error: %v", m.err)
+	}
+
+	return lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		m.list.View(),
+		m.viewport.View(),
+	)
+}
+
+// runTUI browses and previews themes, the default command: tui
+func runTUI(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("tui", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usage("tui")
+	}
+
+	if needsSetup() {
+		if err := runWizard(); err != nil {
+			return err
+		}
+	}
+
+	m := initialModel()
+	unlock, err := lockInstance(m.alacritty.configFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := m.backupConfig(); err != nil {
+		return fmt.Errorf("couldn't back up the config: %w", err)
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	// Closing the terminal hangs up on alacritheme, which then quits like a
+	// kill does
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		<-hangup
+		p.Quit()
+	}()
+
+	final, err := p.Run()
+	quit, ok := final.(model)
+	if !ok || !quit.quit {
+		// Killed, hung up on or crashed rather than quit with q or enter, a
+		// previewed theme is restored all the same. Bubble Tea recovers
+		// from a panic and returns no model.
+		m.previews.stop()
+		if restoreErr := restoreAll(m.backends); restoreErr != nil {
+			return fmt.Errorf("couldn't restore %s, alacritheme rollback restores its backup: %w", m.alacritty.configFile, restoreErr)
+		}
+		quit.restored = true
+	}
+	m.scripts.close()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("alacritheme crashed, the original config was restored to %s", m.alacritty.configFile)
+	}
+
+	printExitSummary(quit)
+	return nil
+}
+
+func main() {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err == nil {
+		err = runCommand(args)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runMigrate converts the YAML config of Alacritty before 0.13 to TOML with
+// alacritty migrate, which converts its imports too. Without an Alacritty
+// that can, only the colors, imports and live_config_reload are converted:
+// migrate
+func runMigrate(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("migrate", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usage("migrate")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	legacy, ok := legacyConfig(configFile)
+	if !ok {
+		info("%s isn't a YAML config, there's nothing to migrate.\n", configFile)
+		return nil
+	}
+
+	target := yamlToTOML(legacy)
+	if fileExists(target) {
+		return fmt.Errorf("%s already exists, move it away to migrate %s", target, legacy)
+	}
+
+	// Only Alacritty 0.13 and later read TOML, and know how to convert to it
+	version, err := installedAlacritty()
+	if major, minor, ok := parseAlacrittyVersion(version); err != nil || !ok || major == 0 && minor < 13 {
+		trace("no alacritty migrate (%s), converting the colors, imports and live_config_reload", version)
+		return migrateColors(configFile, legacy, target)
+	}
+
+	migrateArgs := []string{"migrate", "--config-file", legacy}
+	if dryRun {
+		migrateArgs = append(migrateArgs, "--dry-run")
+	}
+	trace("running alacritty %s", strings.Join(migrateArgs, " "))
+	cmd := exec.Command("alacritty", migrateArgs...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("alacritty migrate: %w", err)
+	}
+	if dryRun {
+		result("%s", out)
+		return nil
+	}
+
+	info("Converted %s to %s, which Alacritty reads from now on. %s was left as it was.\n", legacy, target, legacy)
+	if configFile != target {
+		info("Point --config, CONFIG_FILE or config_file at %s.\n", target)
+	}
+	return nil
+}
+
+// migrateColors converts what alacritheme needs of the YAML config at legacy
+// into a TOML config at target, along with the YAML themes it imports. The
+// config's colors go into a theme it imports last, which applying a theme
+// replaces.
+func migrateColors(configFile, legacy, target string) error {
+	colorsFile := strings.TrimSuffix(target, ".toml") + "-colors.toml"
+	if fileExists(colorsFile) {
+		return fmt.Errorf("%s already exists, move it away to migrate %s", colorsFile, legacy)
+	}
+	files, skipped, err := convertLegacyConfig(legacy, colorsFile, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if dryRun {
+			result("# %s\n%s\n", file.path, file.content)
+			continue
+		}
+		if fileExists(file.path) {
+			// e.g. a theme converted before
+			info("Kept %s, which exists already, instead of converting it anew.\n", file.path)
+			continue
+		}
+		if err := writeConfig(file.path, file.content); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		return nil
+	}
+
+	info("Converted the colors, imports and live_config_reload of %s to %s. %s was left as it was.\n", legacy, target, legacy)
+	if len(skipped) > 0 {
+		info("The rest wasn't converted, copy it over by hand, or move %s away and run alacritheme migrate again with Alacritty 0.13 or later: %s.\n", target, strings.Join(skipped, ", "))
+	}
+	if configFile != target {
+		info("Point --config, CONFIG_FILE or config_file at %s.\n", target)
+	}
+	return nil
+}
+
+// convertLegacyConfig converts the colors, imports and live_config_reload of
+// the YAML config at path to TOML, and its imports that hold nothing else,
+// e.g. themes. It returns the TOML files, the config's last, and the keys
+// left out. YAML imports that hold more are left imported as they are, and
+// reported. The colors are written to colorsFile, imported last, unless it's
+// empty. Files in seen were converted already.
+func convertLegacyConfig(path, colorsFile string, seen map[string]bool) ([]generatedFile, []string, error) {
+	seen[path] = true
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	colors, err := parseAlacrittyYAML(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config := make(map[string]interface{})
+	for key, color := range colors {
+		setColor(config, key, color)
+	}
+
+	var files []generatedFile
+	var skipped []string
+	fields, imports, err := legacyTopLevel(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range fields.keys {
+		switch key {
+		case "colors", "import":
+		case "live_config_reload":
+			reload, err := strconv.ParseBool(yamlString(fields.values[key]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: live_config_reload is %s, not true or false", path, yamlString(fields.values[key]))
+			}
+			config[key] = reload
+		default:
+			// Mappings that only hold anchors are there for colors to alias
+			if !fields.anchors[key] {
+				skipped = append(skipped, key)
+			}
+		}
+	}
+
+	converted := make([]interface{}, 0, len(imports))
+	for _, imp := range imports {
+		ext := strings.ToLower(filepath.Ext(imp))
+		if ext != ".yml" && ext != ".yaml" {
+			converted = append(converted, imp)
+			continue
+		}
+
+		resolved := resolveImport(expandHome(imp), path)
+		if !seen[resolved] {
+			imported, rest, err := convertLegacyConfig(resolved, "", seen)
+			if os.IsNotExist(err) {
+				// Alacritty skips missing imports too
+				rest = []string{"missing"}
+			} else if err != nil {
+				return nil, nil, err
+			}
+			if len(rest) > 0 {
+				skipped = append(skipped, "the import of "+imp)
+				converted = append(converted, imp)
+				continue
+			}
+			files = append(files, imported...)
+		}
+		converted = append(converted, yamlToTOML(imp))
+	}
+	if colors, ok := config["colors"]; ok && colorsFile != "" {
+		theme, err := encodeConfig(map[string]interface{}{"colors": colors})
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, generatedFile{colorsFile, theme})
+		converted = append(converted, colorsFile)
+		delete(config, "colors")
+	}
+	if len(converted) > 0 {
+		// Where Alacritty 0.13 reads it, applying a theme moves it for later
+		// versions
+		config["import"] = converted
+	}
+
+	encoded, err := encodeConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(files, generatedFile{yamlToTOML(path), encoded}), skipped, nil
+}
+
+// legacyFields are the top-level keys of a YAML file
+type legacyFields struct {
+	// keys are in the order of the file
+	keys   []string
+	values map[string]string
+	// anchors are the keys whose mappings hold anchored ones
+	anchors map[string]bool
+}
+
+// legacyTopLevel reads the top-level keys of a YAML config, and the paths
+// its import lists, whether as a block or a flow sequence
+func legacyTopLevel(content []byte) (legacyFields, []string, error) {
+	fields := legacyFields{values: make(map[string]string), anchors: make(map[string]bool)}
+	var imports []string
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok {
+			if current == "import" {
+				imports = append(imports, yamlPath(item))
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(trimmed, ":")
+		if line[0] == ' ' || line[0] == '\t' {
+			if strings.HasPrefix(strings.TrimSpace(value), "&") {
+				fields.anchors[current] = true
+			}
+			continue
+		}
+
+		current = strings.Trim(strings.TrimSpace(key), `"'`)
+		fields.keys = append(fields.keys, current)
+		fields.values[current] = value
+		if list, ok := strings.CutPrefix(strings.TrimSpace(value), "["); current == "import" && ok {
+			list, _, _ = strings.Cut(list, "]")
+			for _, item := range strings.Split(list, ",") {
+				if path := yamlPath(item); path != "" {
+					imports = append(imports, path)
+				}
+			}
+		}
+	}
+
+	return fields, imports, scanner.Err()
+}
+
+// yamlPath extracts a path from a YAML value, which may be quoted, and
+// unquoted have spaces
+func yamlPath(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return yamlString(value)
+	}
+	value, _, _ = strings.Cut(value, " #")
+	return strings.TrimSpace(value)
+}
+
+// yamlToTOML returns path with its YAML extension replaced by .toml
+func yamlToTOML(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".toml"
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// notify shows a desktop notification, with a Revert action calling revert
+// where the notification server supports actions. It doesn't wait for the
+// notification to be dismissed, and failing to show it isn't an error.
+func notify(title, body string, revert func()) {
+	switch runtime.GOOS {
+	case "darwin":
+		// Notifications of scripts can't have actions
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body+" Run alacritheme rollback to revert it."), strconv.Quote(title))
+		trace("running osascript -e %s", script)
+		if err := exec.Command("osascript", "-e", script).Start(); err != nil {
+			trace("couldn't notify: %v", err)
+		}
+	case "windows":
+		trace("no desktop notifications on Windows")
+	default:
+		notifySend, err := exec.LookPath("notify-send")
+		if err != nil {
+			trace("couldn't notify: %v", err)
+			return
+		}
+		go func() {
+			// --action needs libnotify 0.7.9, older ones fail on it
+			trace("running notify-send --action=revert=Revert --wait %q %q", title, body)
+			out, err := exec.Command(notifySend, "--app-name=alacritheme", "--action=revert=Revert", "--wait", title, body).Output()
+			if err != nil {
+				trace("notify-send --action failed, notifying without it: %v", err)
+				if err := exec.Command(notifySend, "--app-name=alacritheme", title, body+" Run alacritheme rollback to revert it.").Run(); err != nil {
+					trace("couldn't notify: %v", err)
+				}
+				return
+			}
+			if strings.TrimSpace(string(out)) == "revert" {
+				revert()
+			}
+		}()
+	}
+}
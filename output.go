@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// verbosity controls how much subcommands print
+type verbosity int
+
+const (
+	quietOutput verbosity = iota
+	normalOutput
+	verboseOutput
+)
+
+var outputLevel = normalOutput
+
+// result prints essential output, which scripts rely on, at every level
+func result(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stdout, format, a...)
+}
+
+// info prints human-friendly output, hidden by --quiet
+func info(format string, a ...interface{}) {
+	if outputLevel >= normalOutput {
+		fmt.Fprintf(os.Stdout, format, a...)
+	}
+}
+
+// trace prints every file operation, shown only with --verbose
+func trace(format string, a ...interface{}) {
+	if outputLevel >= verboseOutput {
+		fmt.Fprintf(os.Stderr, "> "+format+"\n", a...)
+	}
+}
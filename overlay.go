@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// managedTheme reports whether the theme at path was downloaded, so it's
+// kept pristine and edits go to a copy in the overlay directory
+func managedTheme(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	sources, _ := loadSources()
+	_, ok := sources[abs]
+	return ok
+}
+
+// overlayRoot is where edited copies of downloaded themes are kept, in the
+// same layout as below the themes directory
+func overlayRoot() (string, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "themes"), nil
+}
+
+// overlayPath returns where the overlay copy of the theme or directory at
+// path lives, or path itself when it's not below the themes directory
+func overlayPath(themesDir, path string) string {
+	rel, err := filepath.Rel(themesDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	root, err := overlayRoot()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(root, rel)
+}
+
+// variantBase returns the path that variants of the theme at path are named
+// after and saved next to, which is in the overlay for downloaded themes, in
+// the themes directory for built-in ones and next to the scheme for converted
+// schemes of other terminals
+func variantBase(themesDir, path string) (string, error) {
+	if scheme := convertedSource(path); scheme != "" {
+		return strings.TrimSuffix(scheme, filepath.Ext(scheme)) + ".toml", nil
+	}
+
+	switch {
+	case isBuiltin(path) && themesDir == "":
+		return "", errBuiltinReadOnly
+	case isBuiltin(path):
+		return filepath.Join(themesDir, filepath.Base(path)), nil
+	case managedTheme(path):
+		return overlayPath(themesDir, path), nil
+	}
+
+	return path, nil
+}
+
+// errBuiltinReadOnly is returned when a built-in theme would be changed
+// without a themes directory to save the copy in
+var errBuiltinReadOnly = errors.New("built-in themes are saved into the themes directory when changed, set THEMES_DIR or themes_dir in alacritheme's config")
+
+// editableTheme returns the file to edit for the theme at path. Downloaded
+// themes are copied into the overlay first, and built-in ones into the
+// themes directory, where they replace the built-in theme, unless a copy
+// already exists.
+func editableTheme(themesDir, path string) (string, error) {
+	// Converted schemes are edited at the source
+	if scheme := convertedSource(path); scheme != "" {
+		return scheme, nil
+	}
+
+	var target string
+	switch {
+	case isBuiltin(path) && themesDir == "":
+		return "", errBuiltinReadOnly
+	case isBuiltin(path):
+		target = filepath.Join(themesDir, filepath.Base(path))
+	case managedTheme(path):
+		target = overlayPath(themesDir, path)
+	default:
+		return path, nil
+	}
+
+	if fileExists(target) {
+		return target, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+
+	trace("copying %s to %s", path, target)
+	return target, os.WriteFile(target, content, 0644)
+}
+
+type themeEditedMsg struct {
+	path string
+	err  error
+}
+
+// editTheme opens the theme at path in $VISUAL or $EDITOR, falling back to vi
+func editTheme(path string) tea.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Editors are often given with flags, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return themeEditedMsg{path: path, err: err}
+	})
+}
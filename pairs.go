@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// variantTokens maps a theme name variant to its counterpart
+var variantTokens = map[string]string{
+	"light": "dark",
+	"dark":  "light",
+	"day":   "night",
+	"night": "day",
+}
+
+// loadPairs reads the manually linked light/dark pairs, keyed by theme path
+func loadPairs() (map[string]string, error) {
+	var file struct {
+		Pairs map[string]string `toml:"pairs"`
+	}
+	err := loadState("pairs.toml", &file)
+	if file.Pairs == nil {
+		file.Pairs = make(map[string]string)
+	}
+
+	return file.Pairs, err
+}
+
+// savePairs writes the manually linked pairs back to disk
+func savePairs(pairs map[string]string) error {
+	return saveState("pairs.toml", map[string]interface{}{"pairs": pairs})
+}
+
+// linkPair records a and b as counterparts of each other, dropping any
+// previous links either of them had
+func linkPair(pairs map[string]string, a, b string) {
+	for _, p := range []string{a, b} {
+		if old, ok := pairs[p]; ok {
+			delete(pairs, old)
+			delete(pairs, p)
+		}
+	}
+
+	pairs[a] = b
+	pairs[b] = a
+}
+
+// counterpartNames returns the candidate file names of the light/dark
+// counterpart of a theme, e.g. "solarized-light.toml" -> "solarized-dark.toml"
+func counterpartNames(name string) []string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	var candidates []string
+	parts := splitWords(base)
+	for i, part := range parts {
+		other, ok := variantTokens[strings.ToLower(part)]
+		if !ok {
+			continue
+		}
+
+		swapped := make([]string, len(parts))
+		copy(swapped, parts)
+		swapped[i] = matchCase(other, part)
+		candidates = append(candidates, strings.Join(swapped, "")+ext)
+	}
+
+	return candidates
+}
+
+// splitWords splits a name into words and the separators between them,
+// keeping the separators so the name can be joined back unchanged
+func splitWords(name string) []string {
+	var parts []string
+	start := 0
+	for i, r := range name {
+		if r == '-' || r == '_' || r == ' ' || r == '.' {
+			if i > start {
+				parts = append(parts, name[start:i])
+			}
+			parts = append(parts, string(r))
+			start = i + 1
+		}
+	}
+	if start < len(name) {
+		parts = append(parts, name[start:])
+	}
+
+	return parts
+}
+
+// matchCase returns word cased like ref ("Light" -> "Dark", "LIGHT" -> "DARK")
+func matchCase(word, ref string) string {
+	switch {
+	case ref == strings.ToUpper(ref):
+		return strings.ToUpper(word)
+	case ref[:1] == strings.ToUpper(ref[:1]):
+		return strings.ToUpper(word[:1]) + word[1:]
+	default:
+		return word
+	}
+}
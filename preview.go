@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// runPreview prints a theme's color swatches with truecolor escapes instead
+// of opening the TUI: preview <theme> [--width n]
+func runPreview(args []string) error {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	width := flags.Int("width", 80, "width of the preview in columns")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage("preview")
+	}
+	if *width < 20 {
+		return fmt.Errorf("--width must be at least 20 columns")
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	themesDirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	path, err := resolveTheme(themesDirs, positional[0])
+	if err != nil {
+		return err
+	}
+	trace("resolved theme %s to %s", positional[0], path)
+
+	// Show the theme as it would be applied, with locked colors
+	content, err := readLocked(path, cfg.Locks)
+	if err != nil {
+		return err
+	}
+
+	// lipgloss drops colors when stdout isn't a terminal, but the preview is
+	// meant to be piped into pagers and files as well
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	result("%s\n", renderColorPreview(string(content), *width, cfg.Preview))
+	return nil
+}
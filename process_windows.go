@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// stillActive is the exit code of processes that haven't exited
+const stillActive = 259
+
+// processRunning tells whether the process with pid is running. Opening a
+// process fails once it's gone, but not while others still hold a handle of
+// it, so its exit code is checked too.
+func processRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// It's running when it's only not allowed, e.g. of another user
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
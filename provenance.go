@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// themeSource records where a downloaded theme came from: the collection it
+// belongs to, the URL the file alone can be downloaded from, the upstream
+// revision when known, and the hash of the content as downloaded, which
+// tells upstream changes apart from local ones
+type themeSource struct {
+	Origin string `toml:"origin"`
+	URL    string `toml:"url,omitempty"`
+	Commit string `toml:"commit,omitempty"`
+	SHA256 string `toml:"sha256,omitempty"`
+}
+
+// loadSources reads the downloaded themes, keyed by their path on disk
+func loadSources() (map[string]themeSource, error) {
+	var file struct {
+		Themes map[string]themeSource `toml:"themes"`
+	}
+	err := loadState("sources.toml", &file)
+	if file.Themes == nil {
+		file.Themes = make(map[string]themeSource)
+	}
+
+	return file.Themes, err
+}
+
+// recordSources adds or replaces the sources of the themes at the given paths
+func recordSources(entries map[string]themeSource) error {
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	for path, source := range entries {
+		if abs, err := filepath.Abs(path); err == nil {
+			sources[abs] = source
+		}
+	}
+
+	return saveState("sources.toml", map[string]interface{}{"themes": sources})
+}
+
+// forgetSources removes the themes at the given paths from the downloaded
+// themes
+func forgetSources(paths []string) error {
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			delete(sources, abs)
+		}
+	}
+
+	return saveState("sources.toml", map[string]interface{}{"themes": sources})
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkUpdates compares the downloaded themes with their upstream versions
+// and returns the absolute paths of the ones that changed upstream. The
+// official collection is checked with a single download, other themes with
+// a conditional request each.
+func checkUpdates() (map[string]bool, error) {
+	sources, err := loadSources()
+	if err != nil {
+		return nil, err
+	}
+
+	outdated := make(map[string]bool)
+	var official map[string][]byte
+	for path, source := range sources {
+		if source.SHA256 == "" || !fileExists(path) {
+			continue
+		}
+
+		var latest []byte
+		switch {
+		case source.Origin == officialThemesURL:
+			if official == nil {
+				if official, _, err = officialArchive(); err != nil {
+					return outdated, err
+				}
+			}
+			latest = official[filepath.Base(path)]
+		case source.URL != "":
+			if latest, err = fetch(source.URL); err != nil {
+				trace("couldn't check %s: %v", path, err)
+				continue
+			}
+		}
+
+		if latest != nil && contentHash(latest) != source.SHA256 {
+			outdated[path] = true
+		}
+	}
+
+	return outdated, nil
+}
+
+// updateTheme replaces the downloaded theme at path with its upstream version
+func updateTheme(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	sources, err := loadSources()
+	if err != nil {
+		return err
+	}
+	source, ok := sources[abs]
+	if !ok {
+		return fmt.Errorf("%s wasn't downloaded by alacritheme", filepath.Base(path))
+	}
+	if source.URL == "" {
+		return fmt.Errorf("%s can only be updated with its collection, run alacritheme sync pull", filepath.Base(path))
+	}
+
+	content, err := fetch(source.URL)
+	if err != nil {
+		return err
+	}
+
+	var scheme ColorScheme
+	if err := toml.Unmarshal(content, &scheme); err != nil {
+		return fmt.Errorf("the upstream %s is not a valid theme: %w", filepath.Base(path), err)
+	}
+
+	trace("writing %s", abs)
+	if err := os.WriteFile(abs, content, 0644); err != nil {
+		return err
+	}
+
+	// The revision of a single file isn't known
+	source.Commit = ""
+	source.SHA256 = contentHash(content)
+	return recordSources(map[string]themeSource{abs: source})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// pywalTitle is how the colors pywal generated are listed in the TUI
+const pywalTitle = "pywal"
+
+// pywalColors is the colors.json pywal writes for the current wallpaper
+type pywalColors struct {
+	Wallpaper string `json:"wallpaper"`
+	Special   struct {
+		Background string `json:"background"`
+		Foreground string `json:"foreground"`
+		Cursor     string `json:"cursor"`
+	} `json:"special"`
+	Colors map[string]string `json:"colors"`
+}
+
+// pywalFile returns where pywal writes its colors, whether or not it exists
+func pywalFile() (string, error) {
+	cache := os.Getenv("XDG_CACHE_HOME")
+	if cache == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cache = filepath.Join(home, ".cache")
+	}
+
+	return filepath.Join(cache, "wal", "colors.json"), nil
+}
+
+// parsePywal reads pywal's colors.json
+func parsePywal(content []byte) (map[string]string, error) {
+	var wal pywalColors
+	if err := json.Unmarshal(content, &wal); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{
+		"primary.background": wal.Special.Background,
+		"primary.foreground": wal.Special.Foreground,
+		"cursor.cursor":      wal.Special.Cursor,
+	}
+	for i, key := range ansiKeys {
+		values[key] = wal.Colors[fmt.Sprintf("color%d", i)]
+	}
+
+	colors := make(map[string]string)
+	for key, value := range values {
+		if value == "" {
+			continue
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color %s: %s", key, value)
+		}
+		colors[key] = c.Hex()
+	}
+
+	return colors, nil
+}
+
+// convertPywal converts pywal's current colors into a theme and returns its
+// path
+func convertPywal() (string, error) {
+	file, err := pywalFile()
+	if err != nil {
+		return "", err
+	}
+
+	return convertFile(file, []importFormat{importFormats["pywal"]})
+}
+
+// runPywal applies the colors pywal generated for the current wallpaper, and
+// with --watch applies them again whenever they change, announcing it with a
+// desktop notification: pywal [--watch] [--interval d] [--notify=false]
+func runPywal(args []string) error {
+	flags := flag.NewFlagSet("pywal", flag.ContinueOnError)
+	watch := flags.Bool("watch", false, "keep running and apply the colors whenever pywal changes them")
+	interval := flags.Duration("interval", time.Second, "how often --watch checks for changes")
+	notifyChanges := flags.Bool("notify", true, "show a desktop notification when --watch applies new colors")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *interval <= 0 {
+		return usage("pywal")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	file, err := pywalFile()
+	if err != nil {
+		return err
+	}
+	if !fileExists(file) {
+		return fmt.Errorf("%s doesn't exist, run wal first", file)
+	}
+
+	// A revert from a notification mustn't interleave with an apply
+	var mu sync.Mutex
+	var applied time.Time
+	for {
+		// pywal rewrites the file, a changed modification time is enough
+		stat, err := os.Stat(file)
+		switch {
+		case err != nil && !*watch:
+			return err
+		case err != nil:
+			trace("couldn't read %s: %v", file, err)
+		case !stat.ModTime().Equal(applied):
+			first := applied.IsZero()
+			applied = stat.ModTime()
+			mu.Lock()
+			// pywal's colors are converted into the same theme every time,
+			// which is put back along with the config
+			before := readGenerated(configFile)
+			if theme, _, err := appliedTheme(configFile); err == nil && theme != "" {
+				if content, err := os.ReadFile(theme); err == nil {
+					before = append(before, generatedFile{theme, content})
+				}
+			}
+			err := applyPywal(configFile)
+			mu.Unlock()
+			switch {
+			case err != nil && !*watch:
+				return err
+			case err != nil:
+				// pywal may be halfway through writing, try again next time
+				trace("couldn't apply %s: %v", file, err)
+				applied = time.Time{}
+			case *watch && *notifyChanges && !first && !dryRun:
+				// Switched in the background, which could go unnoticed
+				wallpaper := pywalWallpaper()
+				notify("alacritheme", fmt.Sprintf("Applied the colors of %s.", wallpaper), func() {
+					mu.Lock()
+					defer mu.Unlock()
+					unlock, err := lockInstance(configFile)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "error: couldn't revert: %v\n", err)
+						return
+					}
+					defer unlock()
+					// Backed up like any change, so the revert can be rolled back
+					if err := backupCurrent(configFile); err != nil {
+						fmt.Fprintf(os.Stderr, "error: couldn't revert: %v\n", err)
+						return
+					}
+					if err := writeGenerated(before); err != nil {
+						fmt.Fprintf(os.Stderr, "error: couldn't revert: %v\n", err)
+						return
+					}
+					info("Reverted to the colors from before %s\n", wallpaper)
+				})
+			}
+		}
+
+		if !*watch {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// applyPywal converts pywal's current colors and applies them
+func applyPywal(configFile string) error {
+	theme, err := convertPywal()
+	if err != nil {
+		return err
+	}
+
+	if wallpaper := pywalWallpaper(); wallpaper != "" && !dryRun {
+		info("Applying the colors of %s\n", wallpaper)
+	}
+	return applyThemeFile(configFile, theme)
+}
+
+// pywalWallpaper returns the wallpaper pywal's colors were generated from
+func pywalWallpaper() string {
+	file, err := pywalFile()
+	if err != nil {
+		return ""
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+
+	var wal pywalColors
+	if json.Unmarshal(content, &wal) != nil {
+		return ""
+	}
+	return filepath.Base(wal.Wallpaper)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// runRandom applies a random theme from the themes directories:
+// random [--dark|--light]
+func runRandom(args []string) error {
+	flags := flag.NewFlagSet("random", flag.ContinueOnError)
+	dark := flags.Bool("dark", false, "only pick dark themes")
+	light := flags.Bool("light", false, "only pick light themes")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || (*dark && *light) {
+		return usage("random")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	dirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	current, content, err := appliedTheme(configFile)
+	if err != nil {
+		return err
+	}
+
+	var candidates []string
+	for i, dir := range dirs {
+		rels, err := collectThemes(dir)
+		if err != nil {
+			return err
+		}
+		for _, rel := range rels {
+			path := filepath.Join(dir, rel)
+			// Edited copies in the overlay replace downloaded themes
+			if edited := overlayPath(dir, path); i == 0 && fileExists(edited) {
+				path = edited
+			}
+			if *dark || *light {
+				scheme, err := readScheme(path)
+				if err != nil {
+					trace("skipping %s: %v", path, err)
+					continue
+				}
+				if isDark, ok := schemeIsDark(scheme); !ok || isDark != *dark {
+					continue
+				}
+			}
+			candidates = append(candidates, path)
+		}
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no matching theme in %s", strings.Join(dirs, ", "))
+	}
+
+	// Pick another theme than the applied one whenever there's a choice
+	others := slices.DeleteFunc(slices.Clone(candidates), func(path string) bool { return path == current })
+	if len(others) > 0 {
+		candidates = others
+	}
+
+	theme := candidates[rand.IntN(len(candidates))]
+	trace("picked %s out of %d themes", theme, len(candidates))
+
+	if err := setTheme(configFile, content, theme); err != nil {
+		return err
+	}
+
+	result("%s\n", filepath.Base(theme))
+	return nil
+}
+
+// schemeIsDark reports whether a scheme has a dark background, judged by
+// its background being darker than its foreground. ok is false when the
+// scheme doesn't set both.
+func schemeIsDark(scheme ColorScheme) (dark, ok bool) {
+	bg, err := parseColor(scheme.Colors.Primary.Background)
+	if err != nil {
+		return false, false
+	}
+	fg, err := parseColor(scheme.Colors.Primary.Foreground)
+	if err != nil {
+		return false, false
+	}
+
+	return relativeLuminance(bg) < relativeLuminance(fg), true
+}
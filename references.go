@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// knownConfigs returns the Alacritty configs worth scanning for references:
+// the managed config, its siblings (often per-profile configs) and the
+// standard locations
+func knownConfigs(configFile string) []string {
+	candidates := []string{configFile}
+
+	if matches, err := filepath.Glob(filepath.Join(filepath.Dir(configFile), "*.toml")); err == nil {
+		candidates = append(candidates, matches...)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		candidates = append(candidates,
+			filepath.Join(configHome, "alacritty", "alacritty.toml"),
+			filepath.Join(home, ".alacritty.toml"),
+		)
+	}
+
+	seen := make(map[string]bool)
+	var configs []string
+	for _, c := range candidates {
+		if abs, err := filepath.Abs(c); err == nil && !seen[abs] {
+			seen[abs] = true
+			configs = append(configs, abs)
+		}
+	}
+
+	return configs
+}
+
+// findReferences returns the configs that import theme, following imports
+// from one config into another. contents overrides what is read from disk,
+// so the managed config is judged by its original content rather than the
+// preview alacritheme has written into it.
+func findReferences(theme string, configs []string, contents map[string][]byte) []string {
+	theme, _ = filepath.Abs(theme)
+
+	var refs []string
+	seen := make(map[string]bool)
+	for len(configs) > 0 {
+		path := configs[0]
+		configs = configs[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		content, ok := contents[path]
+		if !ok {
+			var err error
+			if content, err = os.ReadFile(path); err != nil {
+				continue
+			}
+		}
+
+		var config map[string]interface{}
+		if err := toml.Unmarshal(content, &config); err != nil {
+			continue
+		}
+
+		for _, imp := range configImports(config) {
+			if !filepath.IsAbs(imp) {
+				imp = filepath.Join(filepath.Dir(path), imp)
+			}
+			imp = filepath.Clean(imp)
+
+			if imp == theme {
+				refs = append(refs, path)
+			} else {
+				configs = append(configs, imp)
+			}
+		}
+	}
+
+	return refs
+}
+
+// renderReferences describes which configs import the theme
+func renderReferences(theme string, refs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "References to %s\n\n", filepath.Base(theme))
+
+	if len(refs) == 0 {
+		b.WriteString("No known Alacritty config imports this theme,\nit can be renamed or deleted safely.\n")
+		return b.String()
+	}
+
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "  • %s\n", ref)
+	}
+
+	return b.String()
+}
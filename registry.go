@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// registryTheme is a theme offered by a remote registry. url is where it's
+// downloaded from, a file in the checkout for git registries.
+type registryTheme struct {
+	registry string
+	remote   string
+	name     string
+	url      string
+	// stars is the popularity an index gives the theme, 0 when unknown
+	stars int
+}
+
+// isIndexURL reports whether remote is an HTTP index.json rather than a git
+// repository
+func isIndexURL(remote string) bool {
+	return strings.HasPrefix(remote, "http") && strings.HasSuffix(remote, ".json")
+}
+
+// registryDir is where the themes downloaded from a registry are saved,
+// below the primary themes directory
+func registryDir(themesDir, registry string) string {
+	return filepath.Join(themesDir, registry)
+}
+
+// validateRegistries reports registry names that can't be used as
+// directory names
+func validateRegistries(registries map[string]string) error {
+	for name := range registries {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("registries: %q can't be used as a directory name", name)
+		}
+	}
+
+	return nil
+}
+
+// listRegistries returns the themes every registry offers, sorted by
+// registry and name. Registries that can't be reached are reported in the
+// error, the others are still listed.
+func listRegistries(registries map[string]string) ([]registryTheme, error) {
+	names := make([]string, 0, len(registries))
+	for name := range registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var themes []registryTheme
+	var errs []string
+	for _, name := range names {
+		listed, err := listRegistry(name, registries[name])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		themes = append(themes, listed...)
+	}
+
+	if len(errs) > 0 {
+		return themes, fmt.Errorf("couldn't list registry %s", strings.Join(errs, "; "))
+	}
+	return themes, nil
+}
+
+// listRegistry returns the themes of a registry: the themes of an HTTP
+// index.json, or the themes/*.toml files of a git repository
+func listRegistry(name, remote string) ([]registryTheme, error) {
+	if isIndexURL(remote) {
+		body, err := fetch(remote)
+		if err != nil {
+			return nil, err
+		}
+
+		var index catalogIndex
+		if err := json.Unmarshal(body, &index); err != nil {
+			return nil, fmt.Errorf("%s: %w", remote, err)
+		}
+
+		themes := make([]registryTheme, 0, len(index.Themes))
+		for _, theme := range index.Themes {
+			themes = append(themes, registryTheme{
+				registry: name,
+				remote:   remote,
+				name:     strings.TrimSuffix(filepath.Base(theme.Name), ".toml") + ".toml",
+				url:      theme.URL,
+				stars:    theme.Stars,
+			})
+		}
+		return themes, nil
+	}
+
+	// A repository in a local directory works without the network
+	if _, err := os.Stat(remote); err != nil {
+		if err := requireOnline(remote); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := appStateDir()
+	if err != nil {
+		return nil, err
+	}
+	repo := filepath.Join(state, "registries", name+"-"+remoteKey(remote))
+	if err := checkoutRepo(remote, repo); err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(repo, "themes", "*.toml"))
+	if err == nil && len(files) == 0 {
+		files, err = filepath.Glob(filepath.Join(repo, "*.toml"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	themes := make([]registryTheme, 0, len(files))
+	for _, file := range files {
+		themes = append(themes, registryTheme{registry: name, remote: remote, name: filepath.Base(file), url: file})
+	}
+	return themes, nil
+}
+
+// registryContent returns the content of a registry theme, from the
+// checkout of git registries
+func registryContent(t registryTheme) ([]byte, error) {
+	if filepath.IsAbs(t.url) {
+		return os.ReadFile(t.url)
+	}
+
+	return fetch(t.url)
+}
+
+// downloadRegistryTheme saves a registry theme into the registry's directory
+// below themesDir and returns its path
+func downloadRegistryTheme(t registryTheme, themesDir string) (string, error) {
+	content, err := registryContent(t)
+	if err != nil {
+		return "", err
+	}
+
+	var scheme ColorScheme
+	if err := toml.Unmarshal(content, &scheme); err != nil {
+		return "", fmt.Errorf("%s is not a valid theme: %w", t.name, err)
+	}
+
+	dir := registryDir(themesDir, t.registry)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	target := filepath.Join(dir, t.name)
+	trace("writing %s", target)
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return "", err
+	}
+
+	source := themeSource{Origin: t.remote, SHA256: contentHash(content)}
+	if !filepath.IsAbs(t.url) {
+		source.URL = t.url
+	}
+	return target, recordSources(map[string]themeSource{target: source})
+}
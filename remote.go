@@ -0,0 +1,383 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpClient is shared by every network feature. Its transport honors
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY, and is set up from the [network]
+// section of alacritheme's config on first use.
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+var (
+	networkOnce sync.Once
+	networkErr  error
+)
+
+// configureNetwork applies the proxy and CA bundle from alacritheme's config
+// to httpClient, once
+func configureNetwork() error {
+	networkOnce.Do(func() {
+		cfg, _, err := loadAppConfig()
+		if err != nil {
+			networkErr = err
+			return
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if cfg.Network.Proxy != "" {
+			proxy, err := url.Parse(cfg.Network.Proxy)
+			if err != nil {
+				networkErr = fmt.Errorf("invalid proxy %q: %w", cfg.Network.Proxy, err)
+				return
+			}
+			transport.Proxy = func(req *http.Request) (*url.URL, error) {
+				if bypassProxy(req.URL.Hostname()) {
+					return nil, nil
+				}
+				return proxy, nil
+			}
+		}
+
+		if cfg.Network.CABundle != "" {
+			pem, err := os.ReadFile(cfg.Network.CABundle)
+			if err != nil {
+				networkErr = fmt.Errorf("couldn't read the CA bundle: %w", err)
+				return
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				networkErr = fmt.Errorf("no certificates found in %s", cfg.Network.CABundle)
+				return
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		}
+
+		httpClient.Transport = transport
+	})
+
+	return networkErr
+}
+
+// bypassProxy reports whether NO_PROXY excludes host from the configured proxy
+func bypassProxy(host string) bool {
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.TrimSpace(entry)
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		switch {
+		case entry == "":
+			continue
+		case entry == "*", host == strings.TrimPrefix(entry, "."):
+			return true
+		case strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")):
+			return true
+		}
+	}
+
+	return false
+}
+
+// gitNetworkArgs passes the configured proxy and CA bundle on to git
+func gitNetworkArgs() []string {
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return nil
+	}
+
+	var args []string
+	if cfg.Network.Proxy != "" {
+		args = append(args, "-c", "http.proxy="+cfg.Network.Proxy)
+	}
+	if cfg.Network.CABundle != "" {
+		args = append(args, "-c", "http.sslCAInfo="+cfg.Network.CABundle)
+	}
+
+	return args
+}
+
+// offline disables every network feature, set by --offline or by
+// offline = true in alacritheme's config
+var offline bool
+
+var errOffline = errors.New("network access is disabled in offline mode (--offline or offline = true in alacritheme's config)")
+
+// loadOffline turns offline mode on when alacritheme's config sets it,
+// before --offline is parsed
+func loadOffline() {
+	if cfg, _, err := loadAppConfig(); err == nil && cfg.Offline {
+		offline = true
+	}
+}
+
+// requireOnline fails fast when a feature needs the network in offline mode
+func requireOnline(target string) error {
+	if offline {
+		return fmt.Errorf("can't reach %s: %w", target, errOffline)
+	}
+
+	return nil
+}
+
+// httpCacheDir returns where response bodies and their ETags are cached
+func httpCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "alacritheme", "http"), nil
+}
+
+// newRequest builds a GET request, authenticated with GITHUB_TOKEN when it
+// goes to GitHub
+func newRequest(rawURL string) (*http.Request, error) {
+	if err := requireOnline(rawURL); err != nil {
+		return nil, err
+	}
+	if err := configureNetwork(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "alacritheme")
+
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && isGitHub(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+func isGitHub(u *url.URL) bool {
+	host := u.Hostname()
+	return host == "github.com" || strings.HasSuffix(host, ".github.com") || host == "raw.githubusercontent.com"
+}
+
+// fetch GETs a URL and returns its body. Responses are cached along with their
+// ETag so repeated requests are conditional and a 304 is served from the cache.
+func fetch(rawURL string) ([]byte, error) {
+	return fetchWithProgress(rawURL, nil)
+}
+
+// fetchWithProgress is fetch, reporting the download on p
+func fetchWithProgress(rawURL string, p *progress) ([]byte, error) {
+	req, err := newRequest(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	key := hex.EncodeToString(sum[:])
+	cacheDir, cacheErr := httpCacheDir()
+	bodyFile := filepath.Join(cacheDir, key+".body")
+	etagFile := filepath.Join(cacheDir, key+".etag")
+
+	if cacheErr == nil {
+		if etag, err := os.ReadFile(etagFile); err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	trace("GET %s", rawURL)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		if body, err := os.ReadFile(bodyFile); err == nil {
+			trace("not modified, using cached %s", bodyFile)
+			return body, nil
+		}
+		// Without the body its ETag is of no use, the URL is fetched anew
+		if req.Header.Get("If-None-Match") == "" {
+			return nil, fmt.Errorf("%s answered 304 Not Modified to a request for a fresh copy", rawURL)
+		}
+		trace("cached %s is unreadable, dropping its ETag", bodyFile)
+		if err := os.Remove(etagFile); err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return fetchWithProgress(rawURL, p)
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	p.start(resp.ContentLength)
+	body, err := io.ReadAll(progressReader{resp.Body, p})
+	p.done()
+	if err != nil {
+		return nil, err
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" && cacheErr == nil {
+		// The cache is an optimization, failing to write it isn't an error
+		if os.MkdirAll(cacheDir, 0755) == nil && os.WriteFile(bodyFile, body, 0644) == nil {
+			_ = os.WriteFile(etagFile, []byte(etag), 0644)
+		}
+	}
+
+	return body, nil
+}
+
+// download streams a URL into w without caching, for large files
+func download(rawURL string, w io.Writer) error {
+	req, err := newRequest(rawURL)
+	if err != nil {
+		return err
+	}
+
+	trace("GET %s", rawURL)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// checkResponse turns unsuccessful responses into errors, explaining GitHub
+// rate limiting instead of reporting a bare 403
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" &&
+		(resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+		msg := "GitHub API rate limit exceeded"
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			msg += ", it resets at " + time.Unix(reset, 0).Format("15:04")
+		}
+		if os.Getenv("GITHUB_TOKEN") == "" {
+			msg += " (set GITHUB_TOKEN to raise the limit)"
+		}
+		return fmt.Errorf("%s", msg)
+	}
+
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		return fmt.Errorf("%s: %s, retry after %ss", resp.Request.URL, resp.Status, retry)
+	}
+
+	return fmt.Errorf("%s: %s", resp.Request.URL, resp.Status)
+}
+
+// progress reports how much of a download has arrived on stderr. A nil
+// progress reports nothing.
+type progress struct {
+	label string
+	read  int64
+	total int64
+	shown time.Time
+}
+
+// newProgress returns the progress of a download, or nil when stderr isn't a
+// terminal or output is quiet
+func newProgress(label string) *progress {
+	if outputLevel < normalOutput {
+		return nil
+	}
+	if stat, err := os.Stderr.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	return &progress{label: label}
+}
+
+// start resets the progress for a download of total bytes, -1 if unknown
+func (p *progress) start(total int64) {
+	if p == nil {
+		return
+	}
+
+	p.read, p.total = 0, total
+	p.print()
+}
+
+func (p *progress) add(n int) {
+	if p == nil {
+		return
+	}
+
+	p.read += int64(n)
+	if time.Since(p.shown) >= 100*time.Millisecond {
+		p.print()
+	}
+}
+
+// done prints the final size and ends the progress line
+func (p *progress) done() {
+	if p == nil {
+		return
+	}
+
+	p.print()
+	fmt.Fprintln(os.Stderr)
+}
+
+func (p *progress) print() {
+	p.shown = time.Now()
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %3d%% of %s", p.label, p.read*100/p.total, formatBytes(p.total))
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s %s", p.label, formatBytes(p.read))
+	}
+}
+
+// progressReader adds everything read from r to p
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (pr progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.add(n)
+	return n, err
+}
+
+// formatBytes formats a size in KB or MB
+func formatBytes(n int64) string {
+	if n < 1<<20 {
+		return fmt.Sprintf("%.0f KB", float64(n)/(1<<10))
+	}
+
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
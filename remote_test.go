@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchCache(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	var requests, conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("theme"))
+	}))
+	defer server.Close()
+
+	cacheDir, err := httpCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		before          func()
+		wantRequests    int
+		wantConditional int
+	}{
+		{name: "first fetch", wantRequests: 1},
+		{name: "served from the cache", wantRequests: 2, wantConditional: 1},
+		{
+			name: "fetched anew without the cached body",
+			before: func() {
+				bodies, _ := filepath.Glob(filepath.Join(cacheDir, "*.body"))
+				for _, body := range bodies {
+					os.Remove(body)
+				}
+			},
+			wantRequests:    4,
+			wantConditional: 2,
+		},
+		{name: "cached again", wantRequests: 5, wantConditional: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			body, err := fetch(server.URL + "/theme.toml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != "theme" {
+				t.Errorf("got %q, want %q", body, "theme")
+			}
+			if requests != tt.wantRequests || conditional != tt.wantConditional {
+				t.Errorf("got %d requests, %d conditional, want %d and %d", requests, conditional, tt.wantRequests, tt.wantConditional)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// runRollback restores a backup of the Alacritty config, by default the
+// newest one that differs from the config, or lists the backups:
+// rollback [--list] [n]
+func runRollback(args []string) error {
+	flags := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	list := flags.Bool("list", false, "list the backups, newest first")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 || *list && len(positional) > 0 {
+		return usage("rollback")
+	}
+
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	backups, err := listBackups(configFile)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backups of %s yet, they're saved before alacritheme changes it", configFile)
+	}
+
+	if *list {
+		for i, b := range backups {
+			theme := "no theme"
+			var config map[string]interface{}
+			if content, err := os.ReadFile(b.path); err == nil && toml.Unmarshal(content, &config) == nil {
+				if path := importedTheme(config, configFile); path != "" {
+					theme = filepath.Base(path)
+				}
+			}
+			result("%2d  %s  %s\n", i+1, b.time.Format("2006-01-02 15:04:05"), theme)
+		}
+		return nil
+	}
+
+	current, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var target backup
+	if len(positional) == 1 {
+		n, err := strconv.Atoi(positional[0])
+		if err != nil || n < 1 || n > len(backups) {
+			return fmt.Errorf("%q is not a backup, pick one of 1 to %d from rollback --list", positional[0], len(backups))
+		}
+		target = backups[n-1]
+	} else {
+		for _, b := range backups {
+			content, err := os.ReadFile(b.path)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(content, current) {
+				target = b
+				break
+			}
+		}
+		if target.path == "" {
+			info("Nothing to roll back, every backup matches %s.\n", configFile)
+			return nil
+		}
+	}
+
+	content, err := os.ReadFile(target.path)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		result("%s", unifiedDiff(configFile, configFile, string(current), string(content)))
+		return nil
+	}
+
+	unlock, err := lockInstance(configFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// The config as it is now is backed up too, so the rollback can be
+	// rolled back
+	if err := backupCurrent(configFile); err != nil {
+		return err
+	}
+	if err := writeConfig(configFile, content); err != nil {
+		return err
+	}
+
+	info("Rolled %s back to the backup from %s.\n", configFile, target.time.Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/key"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Events scripts can subscribe to with alacritheme.on
+const (
+	// themePreviewedEvent fires whenever the config is rewritten for a preview
+	themePreviewedEvent = "theme_previewed"
+	// themeAppliedEvent fires when a theme is kept by pressing enter, or
+	// applied by apply, random, toggle, install or pywal
+	themeAppliedEvent = "theme_applied"
+)
+
+// scriptEngine runs the user's Lua scripts from the scripts directory. Scripts
+// talk to alacritheme through the global alacritheme table:
+//
+//	alacritheme.on(event, function(theme) ... end)
+//	alacritheme.action(key, description, function(theme) return "status" end)
+//	alacritheme.exporter(format, extension, function(theme) return "text" end)
+type scriptEngine struct {
+	mu        sync.Mutex
+	state     *lua.LState
+	handlers  map[string][]*lua.LFunction
+	actions   []scriptAction
+	exporters map[string]exportFormat
+}
+
+// loadedScripts loads the scripts once, for the TUI and the commands that
+// apply or export themes
+var loadedScripts = sync.OnceValues(loadScripts)
+
+// scriptAction is a key binding registered by a script
+type scriptAction struct {
+	binding key.Binding
+	fn      *lua.LFunction
+}
+
+// loadScripts runs every *.lua file in the scripts directory, returning nil
+// when there are none so scripting stays entirely optional
+func loadScripts() (*scriptEngine, error) {
+	dir, err := appConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "scripts", "*.lua"))
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	e := &scriptEngine{
+		state:     lua.NewState(),
+		handlers:  make(map[string][]*lua.LFunction),
+		exporters: make(map[string]exportFormat),
+	}
+
+	api := e.state.NewTable()
+	e.state.SetField(api, "on", e.state.NewFunction(e.luaOn))
+	e.state.SetField(api, "action", e.state.NewFunction(e.luaAction))
+	e.state.SetField(api, "exporter", e.state.NewFunction(e.luaExporter))
+	e.state.SetGlobal("alacritheme", api)
+
+	for _, file := range files {
+		if err := e.state.DoFile(file); err != nil {
+			e.state.Close()
+			return nil, fmt.Errorf("script %s: %w", filepath.Base(file), err)
+		}
+	}
+
+	return e, nil
+}
+
+func (e *scriptEngine) luaOn(L *lua.LState) int {
+	event := L.CheckString(1)
+	if event != themePreviewedEvent && event != themeAppliedEvent {
+		L.ArgError(1, "unknown event "+event)
+	}
+
+	e.handlers[event] = append(e.handlers[event], L.CheckFunction(2))
+	return 0
+}
+
+func (e *scriptEngine) luaAction(L *lua.LState) int {
+	keyName := L.CheckString(1)
+	description := L.CheckString(2)
+
+	e.actions = append(e.actions, scriptAction{
+		binding: key.NewBinding(key.WithKeys(keyName), key.WithHelp(keyName, description)),
+		fn:      L.CheckFunction(3),
+	})
+	return 0
+}
+
+func (e *scriptEngine) luaExporter(L *lua.LState) int {
+	name := L.CheckString(1)
+	ext := L.CheckString(2)
+	fn := L.CheckFunction(3)
+	if _, ok := exportFormats[name]; ok {
+		L.ArgError(1, "format "+name+" is built in")
+	}
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	e.exporters[name] = exportFormat{ext: ext, write: func(name string, scheme ColorScheme) ([]byte, error) {
+		return e.export(fn, name, scheme)
+	}}
+	return 0
+}
+
+// emit calls the handlers registered for event with the theme at path
+func (e *scriptEngine) emit(event, path string) error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, fn := range e.handlers[event] {
+		theme, err := e.themeTable(path)
+		if err != nil {
+			return err
+		}
+		if err := e.state.CallByParam(lua.P{Fn: fn, Protect: true}, theme); err != nil {
+			return fmt.Errorf("%s handler: %w", event, err)
+		}
+	}
+
+	return nil
+}
+
+// action returns the script action bound to the key, if any
+func (e *scriptEngine) action(keyName string) (scriptAction, bool) {
+	if e == nil {
+		return scriptAction{}, false
+	}
+
+	for _, a := range e.actions {
+		for _, k := range a.binding.Keys() {
+			if k == keyName {
+				return a, true
+			}
+		}
+	}
+
+	return scriptAction{}, false
+}
+
+// run calls the action with the theme at path and returns the status text
+// the script returned, if any
+func (e *scriptEngine) run(a scriptAction, path string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	theme, err := e.themeTable(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := e.state.CallByParam(lua.P{Fn: a.fn, NRet: 1, Protect: true}, theme); err != nil {
+		return "", err
+	}
+
+	ret := e.state.Get(-1)
+	e.state.Pop(1)
+	if ret == lua.LNil {
+		return "", nil
+	}
+
+	return ret.String(), nil
+}
+
+// export calls an exporter with the scheme of the theme called name, which
+// returns the converted theme
+func (e *scriptEngine) export(fn *lua.LFunction, name string, scheme ColorScheme) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, e.schemeTable(name, "", scheme)); err != nil {
+		return nil, err
+	}
+
+	ret := e.state.Get(-1)
+	e.state.Pop(1)
+	text, ok := ret.(lua.LString)
+	if !ok {
+		return nil, fmt.Errorf("exporter returned %s, not a string", ret.Type())
+	}
+
+	return []byte(text), nil
+}
+
+// exporter returns the format a script registered as name, if any
+func (e *scriptEngine) exporter(name string) (exportFormat, bool) {
+	if e == nil {
+		return exportFormat{}, false
+	}
+
+	format, ok := e.exporters[name]
+	return format, ok
+}
+
+// checkKeys reports script actions bound to a key the TUI already handles,
+// which would never reach them, or bound twice
+func (e *scriptEngine) checkKeys(taken []key.Binding) error {
+	if e == nil {
+		return nil
+	}
+
+	used := make(map[string]string)
+	for _, k := range reservedKeys {
+		used[k] = "navigating the list"
+	}
+	for _, binding := range taken {
+		if binding.Enabled() {
+			for _, k := range binding.Keys() {
+				used[k] = binding.Help().Desc
+			}
+		}
+	}
+
+	for _, a := range e.actions {
+		k := a.binding.Keys()[0]
+		if desc, ok := used[k]; ok {
+			return fmt.Errorf("script action %q: %q is already bound to %s", a.binding.Help().Desc, k, desc)
+		}
+		used[k] = a.binding.Help().Desc
+	}
+
+	return nil
+}
+
+// bindings returns the help entries of the script actions
+func (e *scriptEngine) bindings() []key.Binding {
+	if e == nil {
+		return nil
+	}
+
+	bindings := make([]key.Binding, len(e.actions))
+	for i, a := range e.actions {
+		bindings[i] = a.binding
+	}
+
+	return bindings
+}
+
+// themeTable describes the theme at path to scripts as
+// {name, path, colors = {primary = {...}, normal = {...}, bright = {...}}}
+func (e *scriptEngine) themeTable(path string) (*lua.LTable, error) {
+	scheme, err := readScheme(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.schemeTable(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), path, scheme), nil
+}
+
+// schemeTable describes scheme, of the theme called name at path, to scripts
+func (e *scriptEngine) schemeTable(name, path string, scheme ColorScheme) *lua.LTable {
+	L := e.state
+	colors := L.NewTable()
+	for _, slot := range scheme.slots() {
+		group, name, _ := strings.Cut(slot.Key, ".")
+		table, ok := L.GetField(colors, group).(*lua.LTable)
+		if !ok {
+			table = L.NewTable()
+			L.SetField(colors, group, table)
+		}
+		L.SetField(table, name, lua.LString(slot.Color))
+	}
+
+	theme := L.NewTable()
+	L.SetField(theme, "name", lua.LString(name))
+	L.SetField(theme, "path", lua.LString(path))
+	L.SetField(theme, "colors", colors)
+
+	return theme
+}
+
+// close releases the Lua state
+func (e *scriptEngine) close() {
+	if e != nil {
+		e.state.Close()
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+const latestReleaseURL = "https://api.github.com/repos/pehlicd/alacritheme/releases/latest"
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// runSelfUpdate replaces the running binary with the latest release after
+// verifying its checksum
+func runSelfUpdate(args []string) error {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	force := flags.Bool("force", false, "update even if this is a development build")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usage("self-update")
+	}
+
+	body, err := fetch(latestReleaseURL)
+	if err != nil {
+		return fmt.Errorf("couldn't check for releases: %w", err)
+	}
+
+	var latest release
+	if err := json.Unmarshal(body, &latest); err != nil {
+		return err
+	}
+
+	if latest.TagName == version {
+		result("alacritheme is up to date (%s)\n", version)
+		return nil
+	}
+	if version == "dev" && !*force {
+		return fmt.Errorf("this is a development build, run with --force to replace it with %s", latest.TagName)
+	}
+
+	asset, ok := findAsset(latest.Assets, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s/%s", latest.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	sums, ok := findChecksums(latest.Assets)
+	if !ok {
+		return fmt.Errorf("release %s has no checksums file, refusing to update", latest.TagName)
+	}
+
+	info("Downloading %s\n", asset.Name)
+	var archive bytes.Buffer
+	if err := download(asset.URL, &archive); err != nil {
+		return err
+	}
+
+	if err := verifyChecksum(sums.URL, asset.Name, archive.Bytes()); err != nil {
+		return err
+	}
+
+	binary, err := extractBinary(asset.Name, archive.Bytes())
+	if err != nil {
+		return err
+	}
+
+	path, err := replaceExecutable(binary)
+	if err != nil {
+		return err
+	}
+
+	result("updated %s from %s to %s\n", path, version, latest.TagName)
+	return nil
+}
+
+// findAsset picks the release archive built for the given platform
+func findAsset(assets []releaseAsset, goos, goarch string) (releaseAsset, bool) {
+	arches := map[string][]string{
+		"amd64": {"amd64", "x86_64"},
+		"arm64": {"arm64", "aarch64"},
+		"386":   {"386", "i386"},
+		"arm":   {"arm", "armv7", "armv6"},
+	}[goarch]
+	if arches == nil {
+		arches = []string{goarch}
+	}
+
+	for _, asset := range assets {
+		name := strings.ToLower(asset.Name)
+		if strings.Contains(name, "checksum") || strings.HasSuffix(name, ".sig") || !containsWord(name, goos) {
+			continue
+		}
+		for _, arch := range arches {
+			if containsWord(name, arch) {
+				return asset, true
+			}
+		}
+	}
+
+	return releaseAsset{}, false
+}
+
+// containsWord reports whether word is in name between separators, so that
+// "arm" isn't found in "arm64"
+func containsWord(name, word string) bool {
+	for i := 0; i+len(word) <= len(name); i++ {
+		j := i + len(word)
+		if name[i:j] == word && (i == 0 || !isAlnum(name[i-1])) && (j == len(name) || !isAlnum(name[j])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func findChecksums(assets []releaseAsset) (releaseAsset, bool) {
+	for _, asset := range assets {
+		if strings.Contains(strings.ToLower(asset.Name), "checksums") {
+			return asset, true
+		}
+	}
+
+	return releaseAsset{}, false
+}
+
+// verifyChecksum checks data against its entry in a sha256sum style file
+func verifyChecksum(sumsURL, name string, data []byte) error {
+	sums, err := fetch(sumsURL)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			if !strings.EqualFold(fields[0], actual) {
+				return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, fields[0], actual)
+			}
+			trace("verified sha256 of %s", name)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// extractBinary returns the alacritheme executable from a release asset,
+// which is either a .tar.gz or .zip archive or the bare binary
+func extractBinary(name string, data []byte) ([]byte, error) {
+	binaryName := "alacritheme"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if filepath.Base(hdr.Name) == binaryName && hdr.Typeflag == tar.TypeReg {
+				return io.ReadAll(tr)
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) == binaryName {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+	default:
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%s doesn't contain %s", name, binaryName)
+}
+
+// replaceExecutable swaps the running binary for the given one by renaming a
+// temporary file over it, and returns the replaced path
+func replaceExecutable(binary []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+
+	stat, err := os.Stat(exe)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".alacritheme-update-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), stat.Mode()); err != nil {
+		return "", err
+	}
+
+	// Windows can't replace a running executable, but it can rename it
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return "", err
+		}
+	}
+
+	trace("replacing %s", exe)
+	return exe, os.Rename(tmp.Name(), exe)
+}
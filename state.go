@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// appConfigDir returns the directory alacritheme keeps its own files in
+func appConfigDir() (string, error) {
+	config, err := configHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(config, "alacritheme"), nil
+}
+
+// configHome returns XDG_CONFIG_HOME, or ~/.config without it, where
+// alacritheme and the terminals it themes keep their configs on every
+// platform, unlike os.UserConfigDir
+func configHome() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config"), nil
+}
+
+// loadState decodes the named TOML file from the app config directory into v,
+// leaving v untouched if the file doesn't exist yet
+func loadState(name string, v interface{}) error {
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return toml.Unmarshal(content, v)
+}
+
+// saveState encodes v as TOML into the named file in the app config directory
+func saveState(name string, v interface{}) error {
+	dir, err := appConfigDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644)
+}
+
+// appStateDir returns the directory alacritheme keeps backups and other
+// state in
+func appStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "alacritheme"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "state", "alacritheme"), nil
+}
+
+// backupsDir returns the directory backups of Alacritty configs are kept in
+func backupsDir() (string, error) {
+	dir, err := appStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "backups"), nil
+}
+
+// configKey names the state kept for the config at configFile: its file
+// name, to tell them apart in the state directory, and a hash of its
+// absolute path, so configs of the same name in different directories don't
+// share it
+func configKey(configFile string) string {
+	path := configFile
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(path)))
+	return filepath.Base(path) + "-" + hex.EncodeToString(sum[:6])
+}
+
+// backupTimeFormat timestamps backups, sorting them by time as they sort by
+// name
+const backupTimeFormat = "20060102-150405.000"
+
+// defaultBackupsKept is how many backups of a config are kept unless
+// backups.keep says otherwise
+const defaultBackupsKept = 20
+
+// backup is a saved copy of an Alacritty config
+type backup struct {
+	path string
+	time time.Time
+}
+
+// listBackups returns the backups of configFile, newest first
+func listBackups(configFile string) ([]backup, error) {
+	dir, err := backupsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	prefix := configKey(configFile) + "."
+	var backups []backup
+	for i := len(entries) - 1; i >= 0; i-- {
+		name := entries[i].Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || !strings.HasSuffix(stamp, ".bak") {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ".bak"), time.Local)
+		if err != nil {
+			// e.g. the single backup of older versions
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, name), time: t})
+	}
+
+	return backups, nil
+}
+
+// saveBackup writes a timestamped copy of the config content into the
+// backups directory and returns its path, or the path of the newest backup
+// if that has the same content. Only the newest keep backups of the config
+// are kept, defaultBackupsKept when keep is 0.
+func saveBackup(configFile string, content []byte, keep int) (string, error) {
+	backups, err := listBackups(configFile)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		if newest, err := os.ReadFile(backups[0].path); err == nil && bytes.Equal(newest, content) {
+			return backups[0].path, nil
+		}
+	}
+
+	dir, err := backupsDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, configKey(configFile)+"."+time.Now().Format(backupTimeFormat)+".bak")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+
+	if keep <= 0 {
+		keep = defaultBackupsKept
+	}
+	// The new backup is the newest, ahead of the listed ones
+	for i := keep - 1; i < len(backups); i++ {
+		trace("removing old backup %s", backups[i].path)
+		if err := os.Remove(backups[i].path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// backupCurrent saves a backup of the config at configFile as it is now,
+// keeping as many as backups.keep says
+func backupCurrent(configFile string) error {
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, err = saveBackup(configFile, content, cfg.Backups.Keep)
+	return err
+}
+
+// lockGrace is how long a lock without a pid is taken to be held, by a
+// process that has yet to write its pid into it
+const lockGrace = 2 * time.Second
+
+// lockInstance takes the lock of configFile for this process, so a second
+// alacritheme previewing themes on it doesn't overwrite the previews of the
+// first and back them up as the original config, and commands applying a
+// theme don't write it while the TUI previews. A lock left behind by a
+// process that's no longer running is taken over, one this process holds
+// already is kept. It returns the function releasing the lock.
+func lockInstance(configFile string) (func(), error) {
+	dir, err := appStateDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, configKey(configFile)+".lock")
+
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			// Released in the meantime
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			// Taken, with the pid about to be written, unless it was taken by
+			// a process that died before writing it
+			stat, statErr := os.Stat(path)
+			if os.IsNotExist(statErr) {
+				continue
+			} else if statErr != nil {
+				return nil, statErr
+			}
+			if time.Since(stat.ModTime()) < lockGrace {
+				time.Sleep(lockGrace / 20)
+				continue
+			}
+		} else if pid == os.Getpid() {
+			// Released by whoever took it
+			return func() {}, nil
+		} else if processRunning(pid) {
+			return nil, fmt.Errorf("another alacritheme (pid %d) is changing %s, quit it first or remove %s if it isn't running", pid, configFile, path)
+		}
+
+		trace("removing stale lock %s", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
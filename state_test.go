@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockInstance(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  bool
+		content string
+		age     time.Duration
+		wantErr bool
+	}{
+		{name: "free"},
+		{name: "held by a running process", exists: true, content: strconv.Itoa(os.Getppid()) + "\n", wantErr: true},
+		{name: "left by a process that's gone", exists: true, content: "999999999\n"},
+		{name: "without a pid for longer than the grace period", exists: true, age: lockGrace},
+		{name: "with garbage for longer than the grace period", exists: true, content: "x", age: lockGrace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			configFile := filepath.Join(t.TempDir(), "alacritty.toml")
+			dir, err := appStateDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, configKey(configFile)+".lock")
+			if tt.exists {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				then := time.Now().Add(-tt.age)
+				if err := os.Chtimes(path, then, then); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			unlock, err := lockInstance(configFile)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "another alacritheme") {
+					t.Fatalf("got error %v, want the lock to be held", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := os.ReadFile(path)
+			if got := strings.TrimSpace(string(content)); got != strconv.Itoa(os.Getpid()) {
+				t.Errorf("lock holds %q, want this process's pid", got)
+			}
+			unlock()
+			if fileExists(path) {
+				t.Error("lock left behind after unlocking")
+			}
+		})
+	}
+}
+
+func TestLockInstanceWaitsForThePid(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	configFile := filepath.Join(t.TempDir(), "alacritty.toml")
+	dir, err := appStateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, configKey(configFile)+".lock")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The process taking the lock writes its pid right after
+	go func() {
+		time.Sleep(lockGrace / 10)
+		os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())+"\n"), 0644)
+	}()
+	if _, err := lockInstance(configFile); err == nil || !strings.Contains(err.Error(), "another alacritheme") {
+		t.Fatalf("got error %v, want the lock to be held", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// hueBins is the number of 30° buckets in the hue histogram
+const hueBins = 12
+
+// grayChroma is the HCL chroma below which a color counts as gray and is
+// left out of the hue histogram
+const grayChroma = 0.08
+
+// saturationBands are the labels of the saturation distribution, by HSL
+// saturation below 1/3, 2/3 and above
+var saturationBands = []string{"muted", "moderate", "vivid"}
+
+// paletteStats summarizes the colors of a theme
+type paletteStats struct {
+	unique       int
+	total        int
+	minLightness float64
+	maxLightness float64
+	darkest      string
+	lightest     string
+	saturation   [3]int
+	hues         [hueBins]int
+	grays        int
+}
+
+// computeStats collects the statistics of all parseable colors of a theme
+func computeStats(scheme ColorScheme) paletteStats {
+	stats := paletteStats{minLightness: math.Inf(1), maxLightness: math.Inf(-1)}
+	seen := make(map[string]bool)
+
+	for _, slot := range scheme.slots() {
+		c, err := parseColor(slot.Color)
+		if err != nil {
+			continue
+		}
+		stats.total++
+
+		hex := c.Hex()
+		if seen[hex] {
+			continue
+		}
+		seen[hex] = true
+		stats.unique++
+
+		h, chroma, l := c.Hcl()
+		if l < stats.minLightness {
+			stats.minLightness, stats.darkest = l, hex
+		}
+		if l > stats.maxLightness {
+			stats.maxLightness, stats.lightest = l, hex
+		}
+
+		_, s, _ := c.Hsl()
+		stats.saturation[min(int(s*3), 2)]++
+
+		if chroma < grayChroma {
+			stats.grays++
+		} else {
+			stats.hues[int(h/(360/hueBins))%hueBins]++
+		}
+	}
+
+	return stats
+}
+
+// renderStats renders the statistics of a theme as text with mini bars
+func renderStats(name string, stats paletteStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Palette of %s\n\n", name)
+
+	if stats.unique == 0 {
+		b.WriteString("The theme has no colors.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Unique colors   %d of %d\n", stats.unique, stats.total)
+	fmt.Fprintf(&b, "Lightness       %s %3.0f%%  →  %s %3.0f%%\n\n",
+		swatch(stats.darkest), stats.minLightness*100,
+		swatch(stats.lightest), stats.maxLightness*100,
+	)
+
+	b.WriteString("Saturation\n")
+	for i, band := range saturationBands {
+		fmt.Fprintf(&b, "  %-10s %s %d\n", band, bar(stats.saturation[i], stats.unique, ""), stats.saturation[i])
+	}
+
+	b.WriteString("\nHue\n")
+	for i, count := range stats.hues {
+		start := i * 360 / hueBins
+		mid := colorful.Hsl(float64(start+180/hueBins), 0.7, 0.55).Hex()
+		fmt.Fprintf(&b, "  %3d°–%3d°  %s %d\n", start, start+360/hueBins, bar(count, stats.unique, mid), count)
+	}
+	fmt.Fprintf(&b, "  gray       %s %d\n", bar(stats.grays, stats.unique, ""), stats.grays)
+
+	return b.String()
+}
+
+// bar renders count out of total as a bar of up to 20 cells, in color if set
+func bar(count, total int, color string) string {
+	const width = 20
+	filled := strings.Repeat("█", count*width/total)
+	if count > 0 && filled == "" {
+		filled = "▏"
+	}
+
+	style := lipgloss.NewStyle()
+	if color != "" {
+		style = style.Foreground(lipgloss.Color(color))
+	}
+
+	return style.Render(filled) + strings.Repeat(" ", width-lipgloss.Width(filled))
+}
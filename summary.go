@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// printExitSummary describes the state the config was left in once the TUI
+// exits. --quiet leaves only errors and the diff of --dry-run.
+func printExitSummary(m model) {
+	a := m.alacritty
+
+	switch {
+	case m.err != nil:
+		fmt.Fprintf(os.Stderr, "Exited with an error: %v\n", m.err)
+		if m.restored {
+			info("The original config was restored to %s.\n", a.configFile)
+		}
+	case m.restored && a.created:
+		info("Removed %s again, it didn't exist before.\n", a.configFile)
+		return
+	case m.restored:
+		info("Restored the original config, %s is unchanged.\n", a.configFile)
+		return
+	case m.previewed == "":
+		info("No theme was applied, %s is unchanged.\n", a.configFile)
+		return
+	case dryRun:
+		updated, err := a.updated(m.previewed)
+		if err != nil {
+			info("Dry run, %s is unchanged: %v\n", a.configFile, err)
+			return
+		}
+		info("Dry run, %s is unchanged. Applying %s would change it like this:\n\n", a.configFile, filepath.Base(m.previewed))
+		result("%s", unifiedDiff(a.configFile, a.configFile, string(a.original), string(updated)))
+		return
+	default:
+		info("Applied %s to %s.\n", filepath.Base(m.previewed), a.configFile)
+	}
+
+	if a.created {
+		info("%s didn't exist before, revert with: rm %q\n", a.configFile, a.configFile)
+	} else if a.backupFile != "" {
+		info("Backup of the original config: %s\n", a.backupFile)
+		info("Revert with: cp %q %q\n", a.backupFile, a.configFile)
+	}
+}
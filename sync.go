@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// syncSubdir is the directory below the themes directory that holds the
+// team's themes
+const syncSubdir = "team"
+
+// syncGroupPrefix prefixes the groups created from catalog tags
+const syncGroupPrefix = "team:"
+
+// catalog is catalog.toml at the root of a shared git repository, next to a
+// themes/ directory with the curated themes
+type catalog struct {
+	Defaults catalogDefaults     `toml:"defaults"`
+	Tags     map[string][]string `toml:"tags"`
+}
+
+// catalogDefaults are the themes the team recommends, by file name
+type catalogDefaults struct {
+	Dark  string `toml:"dark,omitempty" json:"dark"`
+	Light string `toml:"light,omitempty" json:"light"`
+}
+
+// catalogIndex is the JSON served by an HTTP catalog
+type catalogIndex struct {
+	Themes []struct {
+		Name string   `json:"name"`
+		URL  string   `json:"url"`
+		Tags []string `json:"tags"`
+		// Stars is how popular the theme is, for registries sorted by
+		// popularity
+		Stars int `json:"stars"`
+	} `json:"themes"`
+	Defaults catalogDefaults `json:"defaults"`
+}
+
+// runSync pulls the team catalog into the themes directory or pushes local
+// changes back: sync pull|push [--remote url]
+func runSync(args []string) error {
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	remote := flags.String("remote", cfg.Sync.Remote, "git repository or HTTP index.json of the team catalog")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || (positional[0] != "pull" && positional[0] != "push") {
+		return usage("sync")
+	}
+	if *remote == "" {
+		return fmt.Errorf("no catalog remote, set sync.remote in alacritheme's config or pass --remote")
+	}
+
+	themesDir, _, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	teamDir := filepath.Join(themesDir, syncSubdir)
+
+	// A catalog in a local directory works without the network
+	if _, err := os.Stat(*remote); err != nil {
+		if err := requireOnline(*remote); err != nil {
+			return err
+		}
+	}
+
+	httpIndex := isIndexURL(*remote)
+	switch {
+	case positional[0] == "pull" && httpIndex:
+		return pullIndex(*remote, teamDir)
+	case positional[0] == "pull":
+		return pullRepo(*remote, teamDir)
+	case httpIndex:
+		return fmt.Errorf("an HTTP catalog is read-only, push needs a git remote")
+	default:
+		return pushRepo(*remote, teamDir)
+	}
+}
+
+// syncRepoDir is where the catalog repository at remote is checked out
+func syncRepoDir(remote string) (string, error) {
+	dir, err := appStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "sync", remoteKey(remote)), nil
+}
+
+// remoteKey names the clone of the repository at remote by a hash of it, so
+// pulls and pushes never go to a repository that's no longer configured
+func remoteKey(remote string) string {
+	sum := sha256.Sum256([]byte(remote))
+	return hex.EncodeToString(sum[:6])
+}
+
+func runGit(dir string, args ...string) error {
+	trace("git %s", strings.Join(args, " "))
+	cmd := exec.Command("git", append(gitNetworkArgs(), args...)...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	if outputLevel >= verboseOutput {
+		cmd.Stdout = os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// checkoutCatalog clones the catalog repository or updates the existing clone
+func checkoutCatalog(remote string) (string, error) {
+	repo, err := syncRepoDir(remote)
+	if err != nil {
+		return "", err
+	}
+
+	return repo, checkoutRepo(remote, repo)
+}
+
+// checkoutRepo clones the git repository at remote into dir, or pulls the
+// existing clone
+func checkoutRepo(remote, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return runGit(dir, "pull", "--ff-only")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+
+	return runGit(filepath.Dir(dir), "clone", remote, dir)
+}
+
+func pullRepo(remote, teamDir string) error {
+	repo, err := checkoutCatalog(remote)
+	if err != nil {
+		return err
+	}
+
+	var cat catalog
+	if content, err := os.ReadFile(filepath.Join(repo, "catalog.toml")); err == nil {
+		if err := toml.Unmarshal(content, &cat); err != nil {
+			return fmt.Errorf("catalog.toml: %w", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(repo, "themes", "*.toml"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(teamDir, 0755); err != nil {
+		return err
+	}
+
+	commit, _ := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	sources := make(map[string]themeSource)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := writeSyncedTheme(teamDir, filepath.Base(file), content); err != nil {
+			return err
+		}
+		sources[filepath.Join(teamDir, filepath.Base(file))] = themeSource{
+			Origin: remote,
+			Commit: strings.TrimSpace(string(commit)),
+			SHA256: contentHash(content),
+		}
+	}
+
+	if err := recordSources(sources); err != nil {
+		return err
+	}
+
+	return applyCatalog(teamDir, len(files), cat)
+}
+
+func pullIndex(remote, teamDir string) error {
+	body, err := fetch(remote)
+	if err != nil {
+		return err
+	}
+
+	var index catalogIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return fmt.Errorf("%s: %w", remote, err)
+	}
+
+	if err := os.MkdirAll(teamDir, 0755); err != nil {
+		return err
+	}
+
+	cat := catalog{Defaults: index.Defaults, Tags: make(map[string][]string)}
+	sources := make(map[string]themeSource)
+	for _, theme := range index.Themes {
+		name := strings.TrimSuffix(filepath.Base(theme.Name), ".toml") + ".toml"
+		content, err := fetch(theme.URL)
+		if err != nil {
+			return fmt.Errorf("%s: %w", theme.Name, err)
+		}
+		if err := writeSyncedTheme(teamDir, name, content); err != nil {
+			return err
+		}
+		cat.Tags[name] = theme.Tags
+		sources[filepath.Join(teamDir, name)] = themeSource{
+			Origin: remote,
+			URL:    theme.URL,
+			SHA256: contentHash(content),
+		}
+	}
+
+	if err := recordSources(sources); err != nil {
+		return err
+	}
+
+	return applyCatalog(teamDir, len(index.Themes), cat)
+}
+
+// writeSyncedTheme validates a catalog theme and writes it into the team dir
+func writeSyncedTheme(teamDir, name string, content []byte) error {
+	var scheme ColorScheme
+	if err := toml.Unmarshal(content, &scheme); err != nil {
+		return fmt.Errorf("%s is not a valid theme: %w", name, err)
+	}
+
+	target := filepath.Join(teamDir, name)
+	trace("writing %s", target)
+	return os.WriteFile(target, content, 0644)
+}
+
+// applyCatalog turns catalog tags into team:<tag> groups and links the
+// recommended defaults as a light/dark pair
+func applyCatalog(teamDir string, count int, cat catalog) error {
+	groups, err := loadGroups()
+	if err != nil {
+		return err
+	}
+
+	for name := range groups {
+		if strings.HasPrefix(name, syncGroupPrefix) {
+			delete(groups, name)
+		}
+	}
+	for theme, tags := range cat.Tags {
+		for _, tag := range tags {
+			group := syncGroupPrefix + tag
+			groups[group] = append(groups[group], filepath.Join(teamDir, theme))
+		}
+	}
+	for _, members := range groups {
+		sort.Strings(members)
+	}
+	if err := saveGroups(groups); err != nil {
+		return err
+	}
+
+	info("Pulled %d themes into %s\n", count, teamDir)
+	if cat.Defaults.Dark != "" && cat.Defaults.Light != "" {
+		pairs, err := loadPairs()
+		if err != nil {
+			return err
+		}
+		linkPair(pairs, filepath.Join(teamDir, cat.Defaults.Dark), filepath.Join(teamDir, cat.Defaults.Light))
+		if err := savePairs(pairs); err != nil {
+			return err
+		}
+		info("Team defaults: %s (dark), %s (light), toggle between them with t\n", cat.Defaults.Dark, cat.Defaults.Light)
+	}
+
+	return nil
+}
+
+// pushRepo copies the team dir and team:<tag> groups into the catalog
+// repository, then commits and pushes them
+func pushRepo(remote, teamDir string) error {
+	repo, err := checkoutCatalog(remote)
+	if err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(teamDir, "*.toml"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "themes"), 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		// Local edits of team themes live in the overlay
+		if edited := overlayPath(filepath.Dir(teamDir), file); fileExists(edited) {
+			file = edited
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(repo, "themes", filepath.Base(file))
+		trace("writing %s", target)
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	// Keep the catalog's defaults, rebuild its tags from the local groups
+	var cat catalog
+	if content, err := os.ReadFile(filepath.Join(repo, "catalog.toml")); err == nil {
+		if err := toml.Unmarshal(content, &cat); err != nil {
+			return fmt.Errorf("catalog.toml: %w", err)
+		}
+	}
+
+	groups, err := loadGroups()
+	if err != nil {
+		return err
+	}
+	cat.Tags = make(map[string][]string)
+	for _, group := range groupNames(groups) {
+		tag, ok := strings.CutPrefix(group, syncGroupPrefix)
+		if !ok {
+			continue
+		}
+		for _, member := range groups[group] {
+			if filepath.Dir(member) == teamDir {
+				cat.Tags[filepath.Base(member)] = append(cat.Tags[filepath.Base(member)], tag)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(cat); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(repo, "catalog.toml"), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if err := runGit(repo, "add", "-A"); err != nil {
+		return err
+	}
+	// Nothing to push when the catalog already matches
+	if exec.Command("git", "-C", repo, "diff", "--cached", "--quiet").Run() == nil {
+		info("The team catalog is already up to date\n")
+		return nil
+	}
+	if err := runGit(repo, "commit", "-m", "Update theme catalog"); err != nil {
+		return err
+	}
+	if err := runGit(repo, "push"); err != nil {
+		return err
+	}
+
+	info("Pushed %d themes to %s\n", len(files), remote)
+	return nil
+}
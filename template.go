@@ -0,0 +1,503 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// templateSuffix marks theme templates, which are rendered into themes
+// rather than listed as themes themselves
+const templateSuffix = ".tmpl.toml"
+
+// A theme template is a theme whose values may contain ${expressions} over
+// the variables declared in its [variables] table:
+//
+//	[variables]
+//	accent = "#ff79c6"
+//	contrast = 1.0
+//
+//	[colors.normal]
+//	magenta = "${accent}"
+//	red = "${rotate(accent, 40)}"
+//	black = "${darken(accent, 0.6 * contrast)}"
+//
+// Expressions support numbers, #rrggbb colors, 'strings', variables,
+// + - * / on numbers, parentheses and the color functions in templateFuncs.
+
+// templateFuncs are the functions available in template expressions
+var templateFuncs = map[string]func(args []interface{}) (interface{}, error){
+	"lighten":    lightnessFunc(1),
+	"darken":     lightnessFunc(-1),
+	"saturate":   chromaFunc(1),
+	"desaturate": chromaFunc(-1),
+	"rotate": func(args []interface{}) (interface{}, error) {
+		c, deg, err := colorAndNumber("rotate", args)
+		if err != nil {
+			return nil, err
+		}
+		h, chroma, l := c.Hcl()
+		return colorful.Hcl(math.Mod(h+deg+360, 360), chroma, l).Clamped(), nil
+	},
+	"mix": func(args []interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("mix expects (color, color, amount)")
+		}
+		a, okA := args[0].(colorful.Color)
+		b, okB := args[1].(colorful.Color)
+		t, okT := args[2].(float64)
+		if !okA || !okB || !okT {
+			return nil, fmt.Errorf("mix expects (color, color, amount)")
+		}
+		return a.BlendLab(b, t).Clamped(), nil
+	},
+}
+
+// lightnessFunc moves a color's lightness by amount (0-1) in the given direction
+func lightnessFunc(sign float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		c, amount, err := colorAndNumber("lighten/darken", args)
+		if err != nil {
+			return nil, err
+		}
+		h, chroma, l := c.Hcl()
+		return colorful.Hcl(h, chroma, math.Max(0, math.Min(1, l+sign*amount))).Clamped(), nil
+	}
+}
+
+// chromaFunc scales a color's chroma by amount (0-1) in the given direction
+func chromaFunc(sign float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		c, amount, err := colorAndNumber("saturate/desaturate", args)
+		if err != nil {
+			return nil, err
+		}
+		h, chroma, l := c.Hcl()
+		return colorful.Hcl(h, math.Max(0, chroma*(1+sign*amount)), l).Clamped(), nil
+	}
+}
+
+func colorAndNumber(name string, args []interface{}) (colorful.Color, float64, error) {
+	if len(args) == 2 {
+		c, okC := args[0].(colorful.Color)
+		n, okN := args[1].(float64)
+		if okC && okN {
+			return c, n, nil
+		}
+	}
+
+	return colorful.Color{}, 0, fmt.Errorf("%s expects (color, number)", name)
+}
+
+// renderTemplate instantiates the template content with its variables, after
+// applying overrides, and returns the theme as TOML
+func renderTemplate(content []byte, overrides map[string]string) ([]byte, error) {
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		return nil, err
+	}
+
+	raw, _ := theme["variables"].(map[string]interface{})
+	delete(theme, "variables")
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	for name, value := range overrides {
+		if _, ok := raw[name]; !ok {
+			return nil, fmt.Errorf("template has no variable %q", name)
+		}
+		raw[name] = value
+	}
+
+	env := &templateEnv{raw: raw, values: make(map[string]interface{}), resolving: make(map[string]bool)}
+	rendered, err := env.renderValue(theme)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(rendered); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// templateEnv resolves variables lazily so they can refer to each other
+type templateEnv struct {
+	raw       map[string]interface{}
+	values    map[string]interface{}
+	resolving map[string]bool
+}
+
+func (env *templateEnv) lookup(name string) (interface{}, error) {
+	if v, ok := env.values[name]; ok {
+		return v, nil
+	}
+
+	raw, ok := env.raw[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %q", name)
+	}
+	if env.resolving[name] {
+		return nil, fmt.Errorf("variable %q refers to itself", name)
+	}
+	env.resolving[name] = true
+	defer delete(env.resolving, name)
+
+	var v interface{}
+	switch r := raw.(type) {
+	case float64:
+		v = r
+	case int64:
+		v = float64(r)
+	case string:
+		// Variables hold either an ${expression} or a plain color, number or text
+		if expr, ok := strings.CutPrefix(r, "${"); ok {
+			var err error
+			if v, err = env.eval(strings.TrimSuffix(expr, "}")); err != nil {
+				return nil, fmt.Errorf("variable %q: %w", name, err)
+			}
+		} else if c, err := parseColor(r); err == nil {
+			v = c
+		} else if n, err := strconv.ParseFloat(r, 64); err == nil {
+			v = n
+		} else {
+			v = r
+		}
+	default:
+		return nil, fmt.Errorf("variable %q has unsupported type %T", name, raw)
+	}
+
+	env.values[name] = v
+	return v, nil
+}
+
+// renderValue replaces the ${expressions} in every string below v
+func (env *templateEnv) renderValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, child := range t {
+			rendered, err := env.renderValue(child)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, child := range t {
+			rendered, err := env.renderValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	case string:
+		return env.renderString(t)
+	default:
+		return v, nil
+	}
+}
+
+func (env *templateEnv) renderString(s string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated expression in %q", s)
+		}
+
+		v, err := env.eval(s[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+
+		out.WriteString(s[:start])
+		out.WriteString(formatTemplateValue(v))
+		s = s[start+end+1:]
+	}
+}
+
+func formatTemplateValue(v interface{}) string {
+	switch t := v.(type) {
+	case colorful.Color:
+		return t.Hex()
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// eval parses and evaluates a single expression
+func (env *templateEnv) eval(expr string) (interface{}, error) {
+	p := &exprParser{env: env, tokens: tokenize(expr)}
+	v, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], expr)
+	}
+
+	return v, nil
+}
+
+// tokenize splits an expression into identifiers, numbers, colors, quoted
+// strings and single character operators
+func tokenize(expr string) []string {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		r := rune(expr[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			end := strings.IndexByte(expr[i+1:], '\'')
+			if end < 0 {
+				end = len(expr) - i - 1
+			}
+			tokens = append(tokens, expr[i:i+end+2])
+			i += end + 2
+		case r == '#' || r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(expr) && (expr[j] == '.' || expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// exprParser is a recursive descent parser evaluating as it goes:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | color | string | name [ "(" args ")" ] | "(" expr ")"
+type exprParser struct {
+	env    *templateEnv
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) expr() (interface{}, error) {
+	left, err := p.term()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.next()
+		var right interface{}
+		if right, err = p.term(); err == nil {
+			left, err = arithmetic(op, left, right)
+		}
+	}
+
+	return left, err
+}
+
+func (p *exprParser) term() (interface{}, error) {
+	left, err := p.unary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.next()
+		var right interface{}
+		if right, err = p.unary(); err == nil {
+			left, err = arithmetic(op, left, right)
+		}
+	}
+
+	return left, err
+}
+
+func (p *exprParser) unary() (interface{}, error) {
+	if p.peek() == "-" {
+		p.next()
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic("-", 0.0, v)
+	}
+
+	return p.primary()
+}
+
+func (p *exprParser) primary() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return v, nil
+	case strings.HasPrefix(t, "#"):
+		return parseColor(t)
+	case strings.HasPrefix(t, "'"):
+		text := strings.Trim(t, "'")
+		if c, err := parseColor(text); err == nil {
+			return c, nil
+		}
+		return text, nil
+	case unicode.IsDigit(rune(t[0])) || t[0] == '.':
+		if strings.HasPrefix(t, "0x") {
+			return parseColor(t)
+		}
+		return strconv.ParseFloat(t, 64)
+	case p.peek() == "(":
+		return p.call(t)
+	default:
+		return p.env.lookup(t)
+	}
+}
+
+func (p *exprParser) call(name string) (interface{}, error) {
+	fn, ok := templateFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+
+	p.next() // (
+	var args []interface{}
+	for p.peek() != ")" {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if p.peek() == "," {
+			p.next()
+		} else if p.peek() != ")" {
+			return nil, fmt.Errorf("expected , or ) in call to %s", name)
+		}
+	}
+	p.next() // )
+
+	v, err := fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	a, okA := left.(float64)
+	b, okB := right.(float64)
+	if !okA || !okB {
+		return nil, fmt.Errorf("%s only works on numbers", op)
+	}
+
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	default:
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	}
+}
+
+// setFlags collects repeated --set name=value flags
+type setFlags map[string]string
+
+func (s setFlags) String() string { return fmt.Sprint(map[string]string(s)) }
+
+func (s setFlags) Set(v string) error {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("expected name=value, got %q", v)
+	}
+	s[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+// runRender instantiates a template into a theme:
+// render <template> [--set name=value]... [--out file]
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	overrides := make(setFlags)
+	flags.Var(overrides, "set", "override a template variable, as name=value")
+	out := flags.String("out", "", "file to write the theme to (default: next to the template)")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage("render")
+	}
+
+	tmpl := positional[0]
+	trace("reading %s", tmpl)
+	content, err := os.ReadFile(tmpl)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := renderTemplate(content, overrides)
+	if err != nil {
+		return fmt.Errorf("%s: %w", tmpl, err)
+	}
+
+	target := *out
+	if target == "" {
+		base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(tmpl), templateSuffix), filepath.Ext(tmpl))
+		target = filepath.Join(filepath.Dir(tmpl), base+".toml")
+	}
+
+	trace("writing %s", target)
+	if err := os.WriteFile(target, rendered, 0644); err != nil {
+		return err
+	}
+
+	result("%s\n", target)
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// colorSlot is a single named color of a scheme, keyed by its TOML path
+// below [colors], e.g. "normal.red"
+type colorSlot struct {
+	Key   string
+	Label string
+	Color string
+}
+
+// slots returns the primary and palette colors of the scheme in display order
+func (s ColorScheme) slots() []colorSlot {
+	c := s.Colors
+	return []colorSlot{
+		{"primary.background", "Background", c.Primary.Background},
+		{"primary.foreground", "Foreground", c.Primary.Foreground},
+		{"normal.black", "Black", c.Normal.Black},
+		{"normal.red", "Red", c.Normal.Red},
+		{"normal.green", "Green", c.Normal.Green},
+		{"normal.yellow", "Yellow", c.Normal.Yellow},
+		{"normal.blue", "Blue", c.Normal.Blue},
+		{"normal.magenta", "Magenta", c.Normal.Magenta},
+		{"normal.cyan", "Cyan", c.Normal.Cyan},
+		{"normal.white", "White", c.Normal.White},
+		{"bright.black", "Bright Black", c.Bright.Black},
+		{"bright.red", "Bright Red", c.Bright.Red},
+		{"bright.green", "Bright Green", c.Bright.Green},
+		{"bright.yellow", "Bright Yellow", c.Bright.Yellow},
+		{"bright.blue", "Bright Blue", c.Bright.Blue},
+		{"bright.magenta", "Bright Magenta", c.Bright.Magenta},
+		{"bright.cyan", "Bright Cyan", c.Bright.Cyan},
+		{"bright.white", "Bright White", c.Bright.White},
+	}
+}
+
+// parseColor parses a theme color written as #rrggbb or 0xrrggbb
+func parseColor(s string) (colorful.Color, error) {
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		s = "#" + rest
+	}
+
+	return colorful.Hex(s)
+}
+
+// setColor sets the color at key (e.g. "normal.red") in a decoded theme file,
+// creating the tables on the way if needed
+func setColor(theme map[string]interface{}, key, color string) {
+	parts := append([]string{"colors"}, strings.Split(key, ".")...)
+
+	table := theme
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			table[part] = next
+		}
+		table = next
+	}
+
+	table[parts[len(parts)-1]] = color
+}
+
+// readScheme reads and parses the theme file at path
+func readScheme(path string) (ColorScheme, error) {
+	var scheme ColorScheme
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return scheme, err
+	}
+
+	return scheme, toml.Unmarshal(content, &scheme)
+}
+
+// resolveTheme finds a theme given either as a path or as a file name, with
+// or without the .toml extension, anywhere below the themes directories
+func resolveTheme(themesDirs []string, name string) (string, error) {
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		if isSchemeFile(name) {
+			return convertScheme(name)
+		}
+		return filepath.Abs(name)
+	}
+
+	want := strings.TrimSuffix(name, ".toml") + ".toml"
+
+	// Edited copies in the overlay take precedence over downloaded themes
+	overlay, err := overlayRoot()
+	if err != nil {
+		return "", err
+	}
+	// Built-in themes come last, so themes on disk replace them
+	dirs := append([]string{overlay}, themesDirs...)
+	if builtin := builtinRoot(); builtin != "" {
+		dirs = append(dirs, builtin)
+	}
+	for _, dir := range dirs {
+		found, err := findTheme(dir, want)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if found != "" {
+			return filepath.Abs(found)
+		}
+	}
+
+	return "", fmt.Errorf("theme %q not found in %s", name, strings.Join(themesDirs, ", "))
+}
+
+// findTheme walks dir for the theme file want, given as a file name or a
+// path relative to dir
+func findTheme(dir, want string) (string, error) {
+	var found string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (d.Name() == want || path == filepath.Join(dir, want)) {
+			found = path
+			return fs.SkipAll
+		}
+		return nil
+	})
+
+	return found, err
+}
+
+// themeNames returns the themes in dirs as accepted by resolveTheme: their
+// path relative to the themes directory, without the .toml extension
+func themeNames(dirs []string) ([]string, error) {
+	if builtin := builtinRoot(); builtin != "" {
+		dirs = append(dirs[:len(dirs):len(dirs)], builtin)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range dirs {
+		rels, err := collectThemes(dir)
+		if err != nil {
+			return names, err
+		}
+		for _, rel := range rels {
+			name := strings.TrimSuffix(filepath.ToSlash(rel), ".toml")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// tmuxSnippet returns where the tmux styles of the applied theme are kept,
+// for source-file in .tmux.conf
+func tmuxSnippet() (string, error) {
+	state, err := appStateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(state, "tmux.conf"), nil
+}
+
+// syncTmux writes the tmux styles of the theme at path to tmuxSnippet and
+// sources them into the running tmux server, if there is one
+func syncTmux(path string) error {
+	scheme, err := readScheme(path)
+	if err != nil {
+		return err
+	}
+	styles, err := exportTmux(schemeName(path), scheme)
+	if err != nil {
+		return err
+	}
+
+	snippet, err := tmuxSnippet()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(snippet), 0755); err != nil {
+		return err
+	}
+	trace("writing %s", snippet)
+	if err := writeConfig(snippet, styles); err != nil {
+		return err
+	}
+
+	return sourceTmux(snippet)
+}
+
+// sourceTmux loads the file at path into the running tmux server. Without
+// tmux or a server, there's nothing to update.
+func sourceTmux(path string) error {
+	tmux, err := exec.LookPath("tmux")
+	if err != nil {
+		trace("not syncing tmux: %v", err)
+		return nil
+	}
+	if exec.Command(tmux, "has-session").Run() != nil {
+		trace("not syncing tmux, no server is running")
+		return nil
+	}
+
+	trace("running tmux source-file %s", path)
+	if out, err := exec.Command(tmux, "source-file", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("tmux source-file: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// tmuxBackend keeps tmux's status line, borders and messages in step with
+// the previewed theme, when sync.tmux is set
+type tmuxBackend struct {
+	// original is the snippet before the first preview, nil if there was
+	// none, and saved tells whether it was read
+	original []byte
+	saved    bool
+}
+
+func (b *tmuxBackend) Name() string { return "tmux" }
+
+// Detect reports whether tmux is installed
+func (b *tmuxBackend) Detect() bool {
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+func (b *tmuxBackend) Preview(path string) error {
+	if dryRun {
+		return nil
+	}
+	if !b.saved {
+		snippet, err := tmuxSnippet()
+		if err != nil {
+			return err
+		}
+		b.original, err = os.ReadFile(snippet)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		b.saved = true
+	}
+
+	return syncTmux(path)
+}
+
+func (b *tmuxBackend) Apply(path string) error {
+	return b.Preview(path)
+}
+
+// Restore sources the styles tmux had before the first preview. Without
+// them, the previewed styles stay until tmux restarts.
+func (b *tmuxBackend) Restore() error {
+	if dryRun || !b.saved {
+		return nil
+	}
+	snippet, err := tmuxSnippet()
+	if err != nil {
+		return err
+	}
+
+	b.saved = false
+	if b.original == nil {
+		trace("removing %s", snippet)
+		return os.Remove(snippet)
+	}
+	if err := writeConfig(snippet, b.original); err != nil {
+		return err
+	}
+	return sourceTmux(snippet)
+}
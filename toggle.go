@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+// runToggle switches the Alacritty config between the light and dark theme
+// set in alacritheme's config: toggle
+func runToggle(args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("toggle", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usage("toggle")
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	_, configFile, err := resolvePaths()
+	if err != nil {
+		return err
+	}
+	dirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	current, content, err := appliedTheme(configFile)
+	if err != nil {
+		return err
+	}
+
+	next, err := toggleTarget(cfg.Toggle, dirs, current)
+	if err != nil {
+		return err
+	}
+	trace("switching from %s to %s", current, next)
+
+	if err := setTheme(configFile, content, next); err != nil {
+		return err
+	}
+
+	result("%s\n", filepath.Base(next))
+	return nil
+}
+
+// toggleTarget returns the theme to switch to from current: the other half
+// of the configured pair, or the counterpart linked in the TUI when no pair
+// is configured
+func toggleTarget(pair toggleConfig, dirs []string, current string) (string, error) {
+	if pair.Dark == "" || pair.Light == "" {
+		pairs, err := loadPairs()
+		if err != nil {
+			return "", err
+		}
+		if counterpart, ok := pairs[current]; ok {
+			return counterpart, nil
+		}
+		return "", fmt.Errorf("no light/dark pair, set dark and light below [toggle] in alacritheme's config")
+	}
+
+	dark, err := resolveTheme(dirs, pair.Dark)
+	if err != nil {
+		return "", err
+	}
+	light, err := resolveTheme(dirs, pair.Light)
+	if err != nil {
+		return "", err
+	}
+
+	// The pair resolves to edited copies in the overlay, if there are any
+	if len(dirs) > 0 {
+		if edited := overlayPath(dirs[0], current); fileExists(edited) {
+			current = edited
+		}
+	}
+
+	switch current {
+	case dark:
+		return light, nil
+	case light:
+		return dark, nil
+	}
+
+	// Some other theme is applied, switch to the opposite of its background
+	if scheme, err := readScheme(current); err == nil {
+		if isDark, ok := schemeIsDark(scheme); ok && isDark {
+			return light, nil
+		}
+	}
+
+	return dark, nil
+}
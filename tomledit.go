@@ -0,0 +1,449 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// tomlDoc is a TOML file edited line by line, so that everything but the
+// edited keys, comments and formatting included, stays as it was
+type tomlDoc struct {
+	lines []string
+}
+
+// tomlLine is a table header or key/value pair of a tomlDoc
+type tomlLine struct {
+	// path is the dotted path of the header, or of the key including its
+	// table, which is table
+	path   string
+	table  string
+	header bool
+	// first and last are the lines the entry spans, and start and end the
+	// offsets of a value in them, before any trailing comment
+	first, last int
+	start, end  int
+}
+
+func parseTomlDoc(content []byte) *tomlDoc {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return &tomlDoc{}
+	}
+
+	return &tomlDoc{lines: strings.Split(text, "\n")}
+}
+
+func (d *tomlDoc) bytes() []byte {
+	if len(d.lines) == 0 {
+		return nil
+	}
+
+	return []byte(strings.Join(d.lines, "\n") + "\n")
+}
+
+// entries returns the headers and keys of the document in order
+func (d *tomlDoc) entries() []tomlLine {
+	var entries []tomlLine
+	table := ""
+	for i := 0; i < len(d.lines); i++ {
+		line := strings.TrimSpace(d.lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			name := strings.TrimLeft(line, "[")
+			if end := strings.Index(name, "]"); end >= 0 {
+				name = name[:end]
+			}
+			table = tomlPath(name)
+			entries = append(entries, tomlLine{path: table, header: true, first: i, last: i})
+			continue
+		}
+
+		eq := keyEnd(d.lines[i])
+		if eq < 0 {
+			continue
+		}
+		path := tomlPath(d.lines[i][:eq])
+		if table != "" {
+			path = table + "." + path
+		}
+		rest := d.lines[i][eq+1:]
+		start := len(d.lines[i]) - len(strings.TrimLeft(rest, " \t"))
+		last, end := d.valueEnd(i, start)
+		entries = append(entries, tomlLine{path: path, table: table, first: i, last: last, start: start, end: end})
+		i = last
+	}
+
+	return entries
+}
+
+// keyEnd returns the offset of the = after the key of line, or -1
+func keyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		case c == '#':
+			return -1
+		}
+	}
+
+	return -1
+}
+
+// tomlPath normalizes a dotted key, dropping whitespace and the quotes of
+// quoted parts
+func tomlPath(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// valueEnd returns the line and offset a value starting at line first and
+// offset start ends at, following arrays, inline tables and multi-line
+// strings across lines
+func (d *tomlDoc) valueEnd(first, start int) (int, int) {
+	depth := 0
+	var quote string
+	for i := first; i < len(d.lines); i++ {
+		line := d.lines[i]
+		end := len(line)
+		j := 0
+		if i == first {
+			j = start
+		}
+		for ; j < len(line); j++ {
+			if quote != "" {
+				if strings.HasPrefix(line[j:], quote) {
+					j += len(quote) - 1
+					quote = ""
+				} else if line[j] == '\\' && quote[0] == '"' {
+					j++
+				}
+				continue
+			}
+
+			switch c := line[j]; c {
+			case '"', '\'':
+				quote = string(c)
+				if strings.HasPrefix(line[j:], strings.Repeat(quote, 3)) {
+					quote = strings.Repeat(quote, 3)
+				}
+				j += len(quote) - 1
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+			case '#':
+				end = j
+				j = len(line)
+			}
+		}
+
+		if depth <= 0 && len(quote) < 3 {
+			return i, len(strings.TrimRight(line[:end], " \t\r"))
+		}
+	}
+
+	last := len(d.lines) - 1
+	return last, len(d.lines[last])
+}
+
+// find returns the entry at path
+func (d *tomlDoc) find(path string, header bool) (tomlLine, bool) {
+	for _, entry := range d.entries() {
+		if entry.path == path && entry.header == header {
+			return entry, true
+		}
+	}
+
+	return tomlLine{}, false
+}
+
+// inlineTable returns an error if path is inside an inline table, e.g.
+// general.import in general = { import = [] }, which can't be edited line by
+// line
+func (d *tomlDoc) inlineTable(path string) error {
+	for _, entry := range d.entries() {
+		if !entry.header && strings.HasPrefix(path, entry.path+".") {
+			return fmt.Errorf("%s is an inline table, write it as a [%s] table for alacritheme to edit %s", entry.path, entry.path, path)
+		}
+	}
+
+	return nil
+}
+
+// set replaces the value of the key at path with value, a TOML value, keeping
+// the key's comment. A missing key is added at the end of its table, next to
+// dotted keys setting the table's keys (general.import = []) if there are
+// any, and the table is added at the end of the document when it's missing
+// too.
+func (d *tomlDoc) set(path string, value interface{}) error {
+	literal, err := tomlValue(value)
+	if err != nil {
+		return err
+	}
+	if err := d.inlineTable(path); err != nil {
+		return err
+	}
+
+	if entry, ok := d.find(path, false); ok {
+		if d.setElement(entry, value) {
+			return nil
+		}
+		line := d.lines[entry.first][:entry.start] + literal + d.lines[entry.last][entry.end:]
+		d.lines = append(d.lines[:entry.first], append([]string{line}, d.lines[entry.last+1:]...)...)
+		return nil
+	}
+
+	table, key := "", path
+	if dot := strings.LastIndex(path, "."); dot >= 0 {
+		table, key = path[:dot], path[dot+1:]
+	}
+	line := key + " = " + literal
+
+	at := -1
+	dotted := false
+	for _, entry := range d.entries() {
+		// Dotted keys of the table, in the table they're written in
+		parent := entry.table
+		if parent != "" {
+			parent += "."
+		}
+		switch {
+		case entry.header && entry.path == table && !dotted:
+			at = entry.last + 1
+		case !entry.header && (entry.table == table || table != "" && strings.HasPrefix(entry.path, table+".") && strings.HasPrefix(table, parent)):
+			// Indented like the keys before it
+			at = entry.last + 1
+			indent := d.lines[entry.first][:len(d.lines[entry.first])-len(strings.TrimLeft(d.lines[entry.first], " \t"))]
+			line = indent + strings.TrimPrefix(path, parent) + " = " + literal
+			dotted = entry.table != table
+		}
+	}
+
+	switch {
+	case table == "" && at < 0:
+		// Before the first table and the comments about it
+		at = len(d.lines)
+		if entries := d.entries(); len(entries) > 0 {
+			at = entries[0].first
+		}
+		for at > 0 && strings.HasPrefix(strings.TrimSpace(d.lines[at-1]), "#") {
+			at--
+		}
+		if at < len(d.lines) && strings.TrimSpace(d.lines[at]) != "" {
+			d.lines = append(d.lines[:at], append([]string{""}, d.lines[at:]...)...)
+		}
+	case at < 0:
+		if len(d.lines) > 0 && strings.TrimSpace(d.lines[len(d.lines)-1]) != "" {
+			d.lines = append(d.lines, "")
+		}
+		d.lines = append(d.lines, "["+table+"]")
+		at = len(d.lines)
+	}
+	d.lines = append(d.lines[:at], append([]string{line}, d.lines[at:]...)...)
+	return nil
+}
+
+// setElement changes the one string of the array at entry that's different
+// in value, so that the rest of the array, its comments and the lines it's
+// spread over, stay as they're written. It reports whether value differs
+// from the array in that way only.
+func (d *tomlDoc) setElement(entry tomlLine, value interface{}) bool {
+	updated, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	lines := append([]string(nil), d.lines[entry.first:entry.last+1]...)
+	lines[len(lines)-1] = lines[len(lines)-1][:entry.end]
+	lines[0] = lines[0][entry.start:]
+	var current struct {
+		V []interface{} `toml:"v"`
+	}
+	if toml.Unmarshal([]byte("v = "+strings.Join(lines, "\n")), &current) != nil || len(current.V) != len(updated) {
+		return false
+	}
+
+	changed := -1
+	for i := range updated {
+		before, ok := current.V[i].(string)
+		after, isString := updated[i].(string)
+		if !ok || !isString {
+			return false
+		}
+		if before != after {
+			if changed >= 0 {
+				return false
+			}
+			changed = i
+		}
+	}
+	if changed < 0 {
+		return true
+	}
+
+	before, after := current.V[changed].(string), updated[changed].(string)
+	for l := entry.first; l <= entry.last; l++ {
+		for _, quoted := range []string{`"` + before + `"`, "'" + before + "'"} {
+			i := strings.Index(d.lines[l], quoted)
+			if i < 0 || l == entry.first && i < entry.start {
+				continue
+			}
+
+			literal, err := tomlValue(after)
+			if err != nil {
+				return false
+			}
+			if quoted[0] == '"' && !strings.ContainsAny(after, "\"\\") {
+				// Quoted the same way as before
+				literal = `"` + after + `"`
+			}
+			d.lines[l] = d.lines[l][:i] + literal + d.lines[l][i+len(quoted):]
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove deletes the key at path, if it's there
+func (d *tomlDoc) remove(path string) error {
+	if err := d.inlineTable(path); err != nil {
+		return err
+	}
+	if entry, ok := d.find(path, false); ok {
+		d.lines = append(d.lines[:entry.first], d.lines[entry.last+1:]...)
+		// Keys removed from the top don't leave the file starting blank
+		for entry.first == 0 && len(d.lines) > 0 && strings.TrimSpace(d.lines[0]) == "" {
+			d.lines = d.lines[1:]
+		}
+	}
+	return nil
+}
+
+// removeTable deletes the table at path with its subtables and the keys
+// setting anything below it. Comments right above the header of the next
+// table are kept, they're likely about that one.
+func (d *tomlDoc) removeTable(path string) {
+	below := func(p string) bool { return p == path || strings.HasPrefix(p, path+".") }
+
+	keep := make([]bool, len(d.lines))
+	for i := range keep {
+		keep[i] = true
+	}
+	entries := d.entries()
+	for i, entry := range entries {
+		if !entry.header && below(entry.path) {
+			for l := entry.first; l <= entry.last; l++ {
+				keep[l] = false
+			}
+		}
+		if !entry.header || !below(entry.path) {
+			continue
+		}
+
+		end := len(d.lines)
+		for _, next := range entries[i+1:] {
+			if next.header {
+				end = next.first
+				break
+			}
+		}
+		for end < len(d.lines) && end > entry.first && strings.HasPrefix(strings.TrimSpace(d.lines[end-1]), "#") {
+			end--
+		}
+		for l := entry.first; l < end; l++ {
+			keep[l] = false
+		}
+	}
+
+	lines := d.lines[:0:0]
+	for i, line := range d.lines {
+		if keep[i] {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	d.lines = lines
+}
+
+// block returns the first and last line of the block between the begin and
+// end comments, which are part of it
+func (d *tomlDoc) block(begin, end string) (int, int, bool) {
+	first := -1
+	for i, line := range d.lines {
+		switch strings.TrimSpace(line) {
+		case begin:
+			first = i
+		case end:
+			if first >= 0 {
+				return first, i, true
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// withoutBlock returns the document without the block between the begin and
+// end comments
+func (d *tomlDoc) withoutBlock(begin, end string) *tomlDoc {
+	lines := append([]string(nil), d.lines...)
+	if first, last, ok := d.block(begin, end); ok {
+		lines = append(lines[:first], lines[last+1:]...)
+	}
+
+	return &tomlDoc{lines: lines}
+}
+
+// replaceBlock puts content between the begin and end comments, in place of
+// what's there, or adds the block at the end of the document after a blank
+// line. Empty content removes the block.
+func (d *tomlDoc) replaceBlock(begin, end string, content []byte) {
+	var block []string
+	if len(content) > 0 {
+		block = append(append([]string{begin}, parseTomlDoc(content).lines...), end)
+	}
+
+	first, last, ok := d.block(begin, end)
+	if !ok {
+		if len(block) > 0 && len(d.lines) > 0 {
+			d.lines = append(d.lines, "")
+		}
+		d.lines = append(d.lines, block...)
+		return
+	}
+
+	lines := append(append(append([]string(nil), d.lines[:first]...), block...), d.lines[last+1:]...)
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	d.lines = lines
+}
+
+// tomlValue encodes value as it's written after a key
+func tomlValue(value interface{}) (string, error) {
+	encoded, err := toml.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(string(encoded), "v = "), "\n"), nil
+}
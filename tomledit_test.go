@@ -0,0 +1,275 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTomlDocSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		path  string
+		value interface{}
+		want  string
+	}{
+		{
+			name:  "replaces a value, keeping its comment",
+			in:    "[general]\nlive_config_reload = false # no flicker\n",
+			path:  "general.live_config_reload",
+			value: true,
+			want:  "[general]\nlive_config_reload = true # no flicker\n",
+		},
+		{
+			name:  "adds a key after the keys of its table, indented like them",
+			in:    "[general]\n  working_directory = \"/tmp\"\n\n[font]\nsize = 12\n",
+			path:  "general.live_config_reload",
+			value: true,
+			want:  "[general]\n  working_directory = \"/tmp\"\n  live_config_reload = true\n\n[font]\nsize = 12\n",
+		},
+		{
+			name:  "adds a missing table at the end",
+			in:    "[font]\nsize = 12\n",
+			path:  "general.import",
+			value: []interface{}{"/themes/nord.toml"},
+			want:  "[font]\nsize = 12\n\n[general]\nimport = ['/themes/nord.toml']\n",
+		},
+		{
+			name:  "adds a top-level key before the first table and its comments",
+			in:    "# Fonts\n[font]\nsize = 12\n",
+			path:  "import",
+			value: []interface{}{"/themes/nord.toml"},
+			want:  "import = ['/themes/nord.toml']\n\n# Fonts\n[font]\nsize = 12\n",
+		},
+		{
+			name:  "replaces a dotted key",
+			in:    "general.import = ['/themes/old.toml'] # theme\n\n[font]\nsize = 12\n",
+			path:  "general.import",
+			value: []interface{}{"/themes/nord.toml"},
+			want:  "general.import = ['/themes/nord.toml'] # theme\n\n[font]\nsize = 12\n",
+		},
+		{
+			name:  "adds a key next to the dotted keys of its table",
+			in:    "general.working_directory = '/tmp'\n\n[font]\nsize = 12\n",
+			path:  "general.live_config_reload",
+			value: true,
+			want:  "general.working_directory = '/tmp'\ngeneral.live_config_reload = true\n\n[font]\nsize = 12\n",
+		},
+		{
+			name:  "adds a key next to dotted keys below a table",
+			in:    "[colors]\nprimary.background = '#000000'\n",
+			path:  "colors.primary.foreground",
+			value: "#ffffff",
+			want:  "[colors]\nprimary.background = '#000000'\nprimary.foreground = '#ffffff'\n",
+		},
+		{
+			name:  "replaces a multi-line array",
+			in:    "import = [\n  \"/a.toml\",\n  \"/b.toml\",\n]\n",
+			path:  "import",
+			value: []interface{}{"/c.toml"},
+			want:  "import = ['/c.toml']\n",
+		},
+		{
+			name:  "creates a document",
+			in:    "",
+			path:  "general.import",
+			value: []interface{}{"/themes/nord.toml"},
+			want:  "[general]\nimport = ['/themes/nord.toml']\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTomlDoc([]byte(tt.in))
+			if err := doc.set(tt.path, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc.bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTomlDocSetElement(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		value []interface{}
+		want  string
+	}{
+		{
+			name:  "changes the one different string of a multi-line array",
+			in:    "import = [\n  \"/keys.toml\", # key bindings\n  \"/themes/old.toml\",\n]\n",
+			value: []interface{}{"/keys.toml", "/themes/nord.toml"},
+			want:  "import = [\n  \"/keys.toml\", # key bindings\n  \"/themes/nord.toml\",\n]\n",
+		},
+		{
+			name:  "keeps single quotes",
+			in:    "import = ['/keys.toml', '/themes/old.toml']\n",
+			value: []interface{}{"/keys.toml", "/themes/nord.toml"},
+			want:  "import = ['/keys.toml', '/themes/nord.toml']\n",
+		},
+		{
+			name:  "rewrites arrays that change in more than one place",
+			in:    "import = [\n  \"/a.toml\",\n  \"/b.toml\",\n]\n",
+			value: []interface{}{"/c.toml", "/d.toml"},
+			want:  "import = ['/c.toml', '/d.toml']\n",
+		},
+		{
+			name:  "rewrites arrays that change in length",
+			in:    "import = [\"/a.toml\"] # theme\n",
+			value: []interface{}{"/a.toml", "/b.toml"},
+			want:  "import = ['/a.toml', '/b.toml'] # theme\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTomlDoc([]byte(tt.in))
+			if err := doc.set("import", tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc.bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTomlDocInlineTable(t *testing.T) {
+	doc := parseTomlDoc([]byte("general = { import = ['/themes/old.toml'] }\n"))
+
+	if err := doc.set("general.import", []interface{}{"/themes/nord.toml"}); err == nil || !strings.Contains(err.Error(), "inline table") {
+		t.Errorf("set: got %v, want an inline table error", err)
+	}
+	if err := doc.remove("general.import"); err == nil || !strings.Contains(err.Error(), "inline table") {
+		t.Errorf("remove: got %v, want an inline table error", err)
+	}
+}
+
+func TestTomlDocRemove(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		path string
+		want string
+	}{
+		{
+			name: "removes a multi-line array",
+			in:   "[general]\nimport = [\n  \"/a.toml\",\n]\nlive_config_reload = true\n",
+			path: "general.import",
+			want: "[general]\nlive_config_reload = true\n",
+		},
+		{
+			name: "removes a dotted key",
+			in:   "general.import = ['/a.toml']\ngeneral.live_config_reload = true\n",
+			path: "general.import",
+			want: "general.live_config_reload = true\n",
+		},
+		{
+			name: "leaves a missing key",
+			in:   "[font]\nsize = 12\n",
+			path: "import",
+			want: "[font]\nsize = 12\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTomlDoc([]byte(tt.in))
+			if err := doc.remove(tt.path); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(doc.bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTomlDocRemoveTable(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "keeps the comments about the next table",
+			in:   "[general]\nlive_config_reload = true\n\n# Fonts\n[font]\nsize = 12\n",
+			want: "# Fonts\n[font]\nsize = 12\n",
+		},
+		{
+			name: "removes subtables and dotted keys below the table",
+			in:   "[font]\nsize = 12\n\n[general]\n[general.sub]\nkey = 1\n\n[window]\ngeneral.x = 1\n",
+			want: "[font]\nsize = 12\n\n[window]\ngeneral.x = 1\n",
+		},
+		{
+			name: "removes an inline table",
+			in:   "general = { live_config_reload = true }\n\n[font]\nsize = 12\n",
+			want: "\n[font]\nsize = 12\n",
+		},
+		{
+			name: "drops blank lines left at the end",
+			in:   "[font]\nsize = 12\n\n[general]\nimport = []\n",
+			want: "[font]\nsize = 12\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTomlDoc([]byte(tt.in))
+			doc.removeTable("general")
+			if got := string(doc.bytes()); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTomlDocReplaceBlock(t *testing.T) {
+	const begin, end = "# begin", "# end"
+	block := []byte("[colors.primary]\nbackground = '#000000'\n")
+
+	tests := []struct {
+		name    string
+		in      string
+		content []byte
+		want    string
+	}{
+		{
+			name:    "adds the block at the end",
+			in:      "[font]\nsize = 12\n",
+			content: block,
+			want:    "[font]\nsize = 12\n\n# begin\n[colors.primary]\nbackground = '#000000'\n# end\n",
+		},
+		{
+			name:    "replaces only what's between the comments",
+			in:      "[font]\nsize = 12\n\n# begin\n[colors.primary]\nbackground = '#ffffff'\n# end\n\n[cursor]\nstyle = 'Beam'\n",
+			content: block,
+			want:    "[font]\nsize = 12\n\n# begin\n[colors.primary]\nbackground = '#000000'\n# end\n\n[cursor]\nstyle = 'Beam'\n",
+		},
+		{
+			name:    "removes the block without content",
+			in:      "[font]\nsize = 12\n\n# begin\n[colors.primary]\nbackground = '#ffffff'\n# end\n",
+			content: nil,
+			want:    "[font]\nsize = 12\n",
+		},
+		{
+			name:    "ignores an end comment without a begin",
+			in:      "# end\n[font]\nsize = 12\n",
+			content: block,
+			want:    "# end\n[font]\nsize = 12\n\n# begin\n[colors.primary]\nbackground = '#000000'\n# end\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseTomlDoc([]byte(tt.in))
+			doc.replaceBlock(begin, end, tt.content)
+			if got := string(doc.bytes()); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// colorTransform changes a single theme color
+type colorTransform func(c colorful.Color) colorful.Color
+
+// Hues, in HCL degrees, that warm and cool colors are pulled towards
+const (
+	warmHue = 50.0
+	coolHue = 250.0
+)
+
+// temperature warms (amount > 0) or cools (amount < 0) a color by pulling its
+// hue towards orange or blue. At ±1 hues move halfway, and grays pick up a
+// faint tint so backgrounds follow along.
+func temperature(amount float64) colorTransform {
+	target := warmHue
+	if amount < 0 {
+		target = coolHue
+	}
+	amount = math.Min(math.Abs(amount), 1)
+
+	return func(c colorful.Color) colorful.Color {
+		h, chroma, l := c.Hcl()
+		if chroma < grayChroma {
+			return colorful.Hcl(target, chroma+amount*0.05, l).Clamped()
+		}
+
+		return colorful.Hcl(h+hueDistance(h, target)*amount*0.5, chroma, l).Clamped()
+	}
+}
+
+// saturation makes a color more vivid (amount > 0) or more muted (amount < 0)
+// by scaling its chroma, e.g. 0.5 is 50% more. Near-gray colors are
+// protected, so backgrounds and grays don't pick up a color cast.
+func saturation(amount float64) colorTransform {
+	amount = math.Max(amount, -1)
+
+	return func(c colorful.Color) colorful.Color {
+		h, chroma, l := c.Hcl()
+		protection := math.Min(math.Max((chroma-grayChroma)/grayChroma, 0), 1)
+
+		return colorful.Hcl(h, chroma*(1+amount*protection), l).Clamped()
+	}
+}
+
+// rotateHue turns every hue by degrees while keeping lightness, so contrast
+// between colors stays the same
+func rotateHue(degrees float64) colorTransform {
+	return func(c colorful.Color) colorful.Color {
+		h, chroma, l := c.Hcl()
+		return hclInGamut(h+degrees, chroma, l)
+	}
+}
+
+// hclInGamut returns the HCL color, lowering its chroma instead of clamping
+// RGB when it's out of gamut, which would change its lightness
+func hclInGamut(h, chroma, l float64) colorful.Color {
+	for ; chroma > 0.001; chroma *= 0.95 {
+		if c := colorful.Hcl(h, chroma, l); c.IsValid() {
+			return c
+		}
+	}
+
+	return colorful.Hcl(h, 0, l).Clamped()
+}
+
+// hueDistance returns the signed shortest angle from hue a to hue b
+func hueDistance(a, b float64) float64 {
+	return math.Mod(b-a+540, 360) - 180
+}
+
+// transformTheme returns the theme file at path with every color below
+// [colors] passed through the transforms, leaving other values such as
+// "CellForeground" alone
+func transformTheme(path string, transforms []colorTransform) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var theme map[string]interface{}
+	if err := toml.Unmarshal(content, &theme); err != nil {
+		return nil, err
+	}
+
+	if colors, ok := theme["colors"].(map[string]interface{}); ok {
+		transformTable(colors, transforms)
+	}
+
+	return theme, nil
+}
+
+func transformTable(table map[string]interface{}, transforms []colorTransform) {
+	for key, value := range table {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			transformTable(v, transforms)
+		case []interface{}:
+			// e.g. colors.indexed_colors, an array of tables
+			for _, elem := range v {
+				if t, ok := elem.(map[string]interface{}); ok {
+					transformTable(t, transforms)
+				}
+			}
+		case string:
+			c, err := parseColor(v)
+			if err != nil {
+				continue
+			}
+			for _, transform := range transforms {
+				c = transform(c)
+			}
+			table[key] = c.Hex()
+		}
+	}
+}
+
+// writeVariant writes theme next to base, with suffix added to its name, and
+// returns the new file's path
+func writeVariant(base, suffix string, theme map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.SetIndentTables(true)
+	if err := encoder.Encode(theme); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(base)
+	variant := strings.TrimSuffix(base, ext) + "-" + suffix + ext
+	return variant, os.WriteFile(variant, buf.Bytes(), 0644)
+}
+
+// runTransform saves a transformed variant of a theme:
+// transform <theme> [--temperature <-1..1>] [--saturation <-1..>] [--hue <degrees>]
+func runTransform(args []string) error {
+	flags := flag.NewFlagSet("transform", flag.ContinueOnError)
+	temp := flags.Float64("temperature", 0, "warm (up to 1) or cool (down to -1) the theme")
+	sat := flags.Float64("saturation", 0, "make colors more vivid (e.g. 0.5) or muted (down to -1)")
+	hue := flags.Float64("hue", 0, "rotate every hue by this many degrees")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usage("transform")
+	}
+
+	var transforms []colorTransform
+	var suffixes []string
+	if *temp != 0 {
+		transforms = append(transforms, temperature(*temp))
+		suffixes = append(suffixes, signedSuffix(*temp, "warm", "cool"))
+	}
+	if *sat != 0 {
+		transforms = append(transforms, saturation(*sat))
+		suffixes = append(suffixes, signedSuffix(*sat, "vivid", "muted"))
+	}
+	if rotation := math.Mod(math.Mod(*hue, 360)+360, 360); rotation != 0 {
+		transforms = append(transforms, rotateHue(rotation))
+		suffixes = append(suffixes, fmt.Sprintf("hue%.0f", rotation))
+	}
+	if len(transforms) == 0 {
+		return fmt.Errorf("no transform given, e.g. --temperature 0.3, --saturation -0.4 or --hue 60")
+	}
+
+	themesDirs, err := resolveThemeDirs()
+	if err != nil {
+		return err
+	}
+
+	path, err := resolveTheme(themesDirs, positional[0])
+	if err != nil {
+		return err
+	}
+	trace("resolved theme %s to %s", positional[0], path)
+
+	theme, err := transformTheme(path, transforms)
+	if err != nil {
+		return err
+	}
+
+	primary := ""
+	if len(themesDirs) > 0 {
+		primary = themesDirs[0]
+	}
+	base, err := variantBase(primary, path)
+	if err != nil {
+		return err
+	}
+	variant, err := writeVariant(base, strings.Join(suffixes, "-"), theme)
+	if err != nil {
+		return err
+	}
+
+	info("Saved the transformed theme to ")
+	result("%s\n", variant)
+	return nil
+}
+
+// signedSuffix names a variant by the direction and size of a transform,
+// e.g. 0.3 -> "warm30"
+func signedSuffix(amount float64, positive, negative string) string {
+	name := positive
+	if amount < 0 {
+		name = negative
+	}
+
+	return fmt.Sprintf("%s%.0f", name, math.Abs(amount)*100)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// updater brings downloaded themes up to date, collecting the sources to
+// record and forget until the end
+type updater struct {
+	known        map[string]themeSource
+	force, prune bool
+	record       map[string]themeSource
+	forget       []string
+}
+
+// runUpdate pulls new and changed themes from the collections, registries
+// and URLs the downloaded themes came from, and reports the ones removed
+// upstream: update [--force] [--prune]
+func runUpdate(args []string) error {
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	force := flags.Bool("force", false, "overwrite themes that were changed locally")
+	prune := flags.Bool("prune", false, "delete themes that were removed upstream, unless they were changed locally")
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usage("update")
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	known, err := loadSources()
+	if err != nil {
+		return err
+	}
+	if len(known) == 0 {
+		info("No downloaded themes, get some with fetch, install or sync pull\n")
+		return nil
+	}
+	u := &updater{known: known, force: *force, prune: *prune, record: make(map[string]themeSource)}
+
+	// Themes are updated along with their collection where it can be listed,
+	// which finds new and removed themes too, and one by one otherwise
+	official := make(map[string]bool)
+	byOrigin := make(map[string][]string)
+	var single []string
+	for path, source := range known {
+		switch {
+		case !fileExists(path):
+			// Deleted since it was downloaded, don't bring it back
+		case source.Origin == officialThemesURL:
+			official[filepath.Dir(path)] = true
+		case registryName(cfg.Registries, source.Origin) != "":
+			byOrigin[source.Origin] = append(byOrigin[source.Origin], path)
+		case source.URL != "":
+			single = append(single, path)
+		default:
+			byOrigin[source.Origin] = append(byOrigin[source.Origin], path)
+		}
+	}
+
+	if len(official) > 0 {
+		if err := u.official(sortedKeys(official)); err != nil {
+			return err
+		}
+	}
+
+	origins := make([]string, 0, len(byOrigin))
+	for origin := range byOrigin {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		name := registryName(cfg.Registries, origin)
+		if name == "" {
+			info("%s: run alacritheme sync pull to update its %d themes\n", origin, len(byOrigin[origin]))
+			continue
+		}
+		if err := u.registry(name, origin, byOrigin[origin]); err != nil {
+			return err
+		}
+	}
+
+	if len(single) > 0 {
+		u.urls(single)
+	}
+
+	if err := recordSources(u.record); err != nil {
+		return err
+	}
+	return forgetSources(u.forget)
+}
+
+// official updates the official collection in each of dirs with a single
+// download
+func (u *updater) official(dirs []string) error {
+	themes, commit, err := officialArchiveWithProgress(newProgress("Downloading alacritty-theme"))
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		// Themes deleted from dir since they were downloaded aren't new
+		latest := make(map[string][]byte)
+		for name, content := range themes {
+			target, _ := filepath.Abs(filepath.Join(dir, name))
+			if _, ok := u.known[target]; !ok || fileExists(target) {
+				latest[name] = content
+			}
+		}
+
+		stats, err := writeOfficialThemes(dir, latest, commit, u.force)
+		if err != nil {
+			return err
+		}
+		stats.merge(u.removed(dir, officialThemesURL, themes))
+		u.report(dir, stats)
+	}
+
+	return nil
+}
+
+// registry updates the themes downloaded from a registry, given by their
+// paths
+func (u *updater) registry(name, remote string, paths []string) error {
+	listed, err := listRegistry(name, remote)
+	if err != nil {
+		return fmt.Errorf("couldn't list registry %s: %w", name, err)
+	}
+
+	latest := make(map[string]registryTheme)
+	for _, t := range listed {
+		latest[t.name] = t
+	}
+
+	var stats fetchStats
+	for _, path := range paths {
+		t, ok := latest[filepath.Base(path)]
+		if !ok {
+			continue
+		}
+
+		content, err := registryContent(t)
+		if err != nil {
+			trace("couldn't check %s: %v", path, err)
+			continue
+		}
+		source := u.known[path]
+		if !filepath.IsAbs(t.url) {
+			source.URL = t.url
+		}
+		stats.merge(u.refresh(path, source, content))
+	}
+
+	// Registries are browsed for new themes, only removals are reported
+	present := make(map[string][]byte)
+	for name := range latest {
+		present[name] = nil
+	}
+	for _, dir := range parentDirs(paths) {
+		stats.merge(u.removed(dir, remote, present))
+	}
+
+	u.report("registry "+name, stats)
+	return nil
+}
+
+// urls updates themes downloaded from a URL of their own, e.g. with install
+func (u *updater) urls(paths []string) {
+	sort.Strings(paths)
+
+	var stats fetchStats
+	for _, path := range paths {
+		source := u.known[path]
+		content, err := fetch(source.URL)
+		if err != nil {
+			trace("couldn't check %s: %v", path, err)
+			continue
+		}
+		stats.merge(u.refresh(path, source, content))
+	}
+
+	u.report("themes installed from a URL", stats)
+}
+
+// refresh replaces the downloaded theme at path with its latest content,
+// unless it was changed locally
+func (u *updater) refresh(path string, source themeSource, latest []byte) fetchStats {
+	var stats fetchStats
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		trace("couldn't read %s: %v", path, err)
+		return stats
+	}
+
+	switch {
+	case bytes.Equal(existing, latest):
+		stats.unchanged++
+	case !u.force && !isPristine(u.known, path, existing):
+		trace("keeping %s, it was changed locally", path)
+		stats.kept++
+		return stats
+	default:
+		var scheme ColorScheme
+		if err := toml.Unmarshal(latest, &scheme); err != nil {
+			trace("not updating %s, the upstream version is not a valid theme: %v", path, err)
+			return stats
+		}
+		trace("writing %s", path)
+		if err := os.WriteFile(path, latest, 0644); err != nil {
+			trace("couldn't write %s: %v", path, err)
+			return stats
+		}
+		stats.updated++
+	}
+
+	// The revision of a single file isn't known
+	source.Commit = ""
+	source.SHA256 = contentHash(latest)
+	u.record[path] = source
+	return stats
+}
+
+// removed returns the themes in dir downloaded from origin that aren't in
+// latest anymore, deleting them with --prune unless they were changed
+// locally
+func (u *updater) removed(dir, origin string, latest map[string][]byte) fetchStats {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fetchStats{}
+	}
+
+	var gone []string
+	for path, source := range u.known {
+		if _, ok := latest[filepath.Base(path)]; !ok && source.Origin == origin && filepath.Dir(path) == abs && fileExists(path) {
+			gone = append(gone, path)
+		}
+	}
+	sort.Strings(gone)
+
+	var stats fetchStats
+	for _, path := range gone {
+		name := filepath.Base(path)
+
+		stats.removed = append(stats.removed, name)
+		if !u.prune {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || !isPristine(u.known, path, content) {
+			trace("keeping %s, it was changed locally", path)
+			continue
+		}
+		trace("removing %s", path)
+		if err := os.Remove(path); err != nil {
+			trace("couldn't remove %s: %v", path, err)
+			continue
+		}
+		u.forget = append(u.forget, path)
+	}
+
+	return stats
+}
+
+// report prints what updating label did, with the new and removed themes
+func (u *updater) report(label string, stats fetchStats) {
+	info("%s: %s\n", label, stats)
+	for _, name := range stats.added {
+		result("+ %s\n", name)
+	}
+	for _, name := range stats.removed {
+		result("- %s\n", name)
+	}
+	if len(stats.removed) > 0 && !u.prune {
+		info("Run alacritheme update --prune to delete the themes removed upstream.\n")
+	}
+	if stats.kept > 0 && !u.force {
+		info("Run alacritheme update --force to overwrite local changes, or edit themes in the TUI to keep them in the overlay.\n")
+	}
+}
+
+// registryName returns the name of the registry at remote, or an empty
+// string when remote isn't a configured registry
+func registryName(registries map[string]string, remote string) string {
+	for name, r := range registries {
+		if r == remote {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// parentDirs returns the directories of paths, without duplicates
+func parentDirs(paths []string) []string {
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		dirs[filepath.Dir(path)] = true
+	}
+
+	return sortedKeys(dirs)
+}
+
+// sortedKeys returns the keys of m, sorted
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
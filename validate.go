@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// cellColors are the colors Alacritty takes besides hex ones, for the cursor
+// and selection colors following the cell
+var cellColors = map[string]bool{"CellForeground": true, "CellBackground": true}
+
+// validateConfig checks content, generated for the config at configFile, the
+// way Alacritty reads the keys alacritheme changes: it has to parse, its
+// imports have to exist, live_config_reload has to be a bool and the colors
+// have to be colors, or be written along with it, as the generated files are.
+// What original, the config before, has already, and the rest of the config,
+// are the user's and left for Alacritty to check.
+func validateConfig(content []byte, configFile string, original map[string]interface{}, generated []generatedFile) error {
+	var config map[string]interface{}
+	if err := toml.Unmarshal(content, &config); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	for _, file := range generated {
+		known[resolveImport(file.path, configFile)] = true
+	}
+	for _, table := range []map[string]interface{}{original, generalTable(original)} {
+		imports, _ := table["import"].([]interface{})
+		for _, imp := range imports {
+			if path, ok := imp.(string); ok {
+				known[resolveImport(expandHome(path), configFile)] = true
+			}
+		}
+	}
+
+	for _, section := range []string{"", "general"} {
+		table, before, key := config, original, ""
+		if section != "" {
+			table, before, key = generalTable(config), generalTable(original), section+"."
+		}
+
+		if value, ok := table["live_config_reload"]; ok {
+			if _, ok := value.(bool); !ok && !reflect.DeepEqual(value, before["live_config_reload"]) {
+				return fmt.Errorf("%slive_config_reload is %v, not true or false", key, value)
+			}
+		}
+
+		value, ok := table["import"]
+		if !ok {
+			continue
+		}
+		imports, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%simport is %v, not a list of paths", key, value)
+		}
+		for _, imp := range imports {
+			path, ok := imp.(string)
+			if !ok {
+				return fmt.Errorf("%simport has %v, not a path", key, imp)
+			}
+			resolved := resolveImport(expandHome(path), configFile)
+			if known[resolved] {
+				continue
+			}
+			if _, err := os.Stat(resolved); err != nil {
+				return fmt.Errorf("%simport has %s: %w", key, path, err)
+			}
+		}
+	}
+
+	return validateColors("colors", config["colors"], original["colors"])
+}
+
+// generalTable returns the [general] table of config, or nil
+func generalTable(config map[string]interface{}) map[string]interface{} {
+	general, _ := config["general"].(map[string]interface{})
+	return general
+}
+
+// validateColors checks that the strings in value, found at key below
+// [colors], are colors, unless they're the same in before. Other values, e.g.
+// transparent_background_colors or the index of an indexed color, aren't
+// colors.
+func validateColors(key string, value, before interface{}) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		table, _ := before.(map[string]interface{})
+		for _, k := range keys {
+			if err := validateColors(key+"."+k, value[k], table[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		elements, _ := before.([]interface{})
+		for i, element := range value {
+			var was interface{}
+			if i < len(elements) {
+				was = elements[i]
+			}
+			if err := validateColors(fmt.Sprintf("%s[%d]", key, i), element, was); err != nil {
+				return err
+			}
+		}
+	case string:
+		if cellColors[value] || value == before {
+			return nil
+		}
+		if _, err := parseColor(value); err != nil {
+			return fmt.Errorf("%s is %q, not a color like #1e1e2e", key, value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// weztermInclude is the Lua module the WezTerm backend generates in
+// WezTerm's config directory, naming the color scheme to use
+const weztermInclude = "alacritheme.lua"
+
+// weztermScheme is the color scheme file the WezTerm backend writes the
+// selected theme to, below WezTerm's colors directory
+const weztermScheme = "alacritheme.toml"
+
+// weztermConfigDir returns WezTerm's config directory
+func weztermConfigDir() (string, error) {
+	config, err := configHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(config, "wezterm"), nil
+}
+
+// weztermConfigFile returns the wezterm.lua WezTerm reads, or an empty
+// string if it has none
+func weztermConfigFile() string {
+	if file := os.Getenv("WEZTERM_CONFIG_FILE"); file != "" {
+		return file
+	}
+
+	var candidates []string
+	if dir, err := weztermConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "wezterm.lua"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".wezterm.lua"))
+	}
+	for _, file := range candidates {
+		if fileExists(file) {
+			return file
+		}
+	}
+	return ""
+}
+
+// generatedFile is a file a backend writes, with its content
+type generatedFile struct {
+	path    string
+	content []byte
+}
+
+// weztermBackend writes the selected theme as a WezTerm color scheme and
+// names it in weztermInclude, which wezterm.lua requires. WezTerm reloads its
+// config when a module it required changes, so previews show up right away.
+type weztermBackend struct {
+	// original are the contents of the generated files before the first
+	// preview, nil for files that didn't exist
+	original []generatedFile
+}
+
+func (b *weztermBackend) Name() string { return "WezTerm" }
+
+// Detect reports whether wezterm.lua requires the generated module, which
+// is set up once by hand
+func (b *weztermBackend) Detect() bool {
+	file := weztermConfigFile()
+	if file == "" {
+		return false
+	}
+
+	content, err := os.ReadFile(file)
+	return err == nil && strings.Contains(string(content), strings.TrimSuffix(weztermInclude, ".lua"))
+}
+
+// files returns the generated files selecting the theme at path, the scheme
+// first so the reload the include triggers finds it
+func (b *weztermBackend) files(path string) ([]generatedFile, error) {
+	dir, err := weztermConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	scheme, err := readScheme(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Its own name keeps it apart from WezTerm's built-in scheme of the
+	// same theme
+	name := schemeName(path) + " (alacritheme)"
+	colors, err := exportWezterm(name, scheme)
+	if err != nil {
+		return nil, err
+	}
+	include := fmt.Sprintf("-- Generated by alacritheme, use it in wezterm.lua with\n-- config.color_scheme = require(\"alacritheme\").color_scheme\nreturn { color_scheme = %q }\n", name)
+
+	return []generatedFile{
+		{filepath.Join(dir, "colors", weztermScheme), colors},
+		{filepath.Join(dir, weztermInclude), []byte(include)},
+	}, nil
+}
+
+func (b *weztermBackend) Preview(path string) error {
+	if dryRun {
+		return nil
+	}
+	files, err := b.files(path)
+	if err != nil {
+		return err
+	}
+
+	if b.original == nil {
+		for _, file := range files {
+			content, err := os.ReadFile(file.path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			b.original = append(b.original, generatedFile{file.path, content})
+		}
+	}
+
+	return writeGenerated(files)
+}
+
+// Apply is the same as a preview, the generated files are kept
+func (b *weztermBackend) Apply(path string) error {
+	return b.Preview(path)
+}
+
+// Restore brings back the generated files as they were, removing the ones
+// that didn't exist
+func (b *weztermBackend) Restore() error {
+	if dryRun || b.original == nil {
+		return nil
+	}
+
+	// The include first, so WezTerm never names a removed scheme
+	for i := len(b.original) - 1; i >= 0; i-- {
+		file := b.original[i]
+		if file.content != nil {
+			continue
+		}
+		trace("removing %s", file.path)
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	var existing []generatedFile
+	for _, file := range b.original {
+		if file.content != nil {
+			existing = append(existing, file)
+		}
+	}
+	b.original = nil
+	return writeGenerated(existing)
+}
+
+// writeGenerated writes files in order, creating their directories
+func writeGenerated(files []generatedFile) error {
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			return err
+		}
+		trace("writing %s", file.path)
+		if err := writeConfig(file.path, file.content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type windowOpenedMsg struct {
+	path string
+	err  error
+}
+
+// openWindow launches a new Alacritty window with a temporary copy of the
+// config with the theme at path applied, leaving the real config untouched.
+// The temporary config is removed when the window is closed while
+// alacritheme is still running, otherwise it's left to the temp dir cleanup.
+func openWindow(config []byte, configFile, path string, opts applyOptions) tea.Cmd {
+	return func() tea.Msg {
+		alacritty, err := exec.LookPath("alacritty")
+		if err != nil {
+			return windowOpenedMsg{path: path, err: err}
+		}
+
+		if opts.mode == applyInclude {
+			// The include file holds the applied theme, the window imports
+			// its own
+			opts.mode = applyByImport
+		}
+		content, err := applyTheme(config, configFile, path, opts)
+		if err != nil {
+			return windowOpenedMsg{path: path, err: err}
+		}
+
+		tmp, err := os.CreateTemp("", "alacritheme-*.toml")
+		if err != nil {
+			return windowOpenedMsg{path: path, err: err}
+		}
+		_, err = tmp.Write(content)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return windowOpenedMsg{path: path, err: err}
+		}
+
+		cmd := exec.Command(alacritty, "--config-file", tmp.Name())
+		if err := cmd.Start(); err != nil {
+			os.Remove(tmp.Name())
+			return windowOpenedMsg{path: path, err: err}
+		}
+
+		go func() {
+			cmd.Wait()
+			os.Remove(tmp.Name())
+		}()
+
+		return windowOpenedMsg{path: path}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type wizardStep int
+
+const (
+	configStep wizardStep = iota
+	themesStep
+	fetchStep
+	doneStep
+)
+
+// wizard walks through the first-run setup: where the Alacritty config and
+// the themes live, and whether to download the official themes
+type wizard struct {
+	step       wizardStep
+	input      textinput.Model
+	configFile string
+	themesDir  string
+	fetch      bool
+	aborted    bool
+}
+
+// needsSetup reports whether this is a first run: no paths in the
+// environment and no alacritheme config yet
+func needsSetup() bool {
+	if themesDirFlag != "" || configFlag != "" || os.Getenv("THEMES_DIR") != "" || os.Getenv("CONFIG_FILE") != "" {
+		return false
+	}
+
+	// The config may exist with other settings only, e.g. locked colors
+	cfg, exists, _ := loadAppConfig()
+	return !exists || (cfg.ThemesDir == "" && len(cfg.ThemesDirs) == 0) || cfg.ConfigFile == ""
+}
+
+func newWizard() wizard {
+	configFile := discoverConfig()
+
+	input := textinput.New()
+	input.SetValue(configFile)
+	input.Focus()
+
+	return wizard{
+		input:      input,
+		configFile: configFile,
+		themesDir:  filepath.Join(alacrittyDir(), "themes"),
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (w wizard) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (w wizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		w.input, cmd = w.input.Update(msg)
+		return w, cmd
+	}
+
+	switch keyMsg.String() {
+	case tea.KeyCtrlC.String(), tea.KeyEsc.String():
+		w.aborted = true
+		return w, tea.Quit
+	}
+
+	switch w.step {
+	case configStep, themesStep:
+		if keyMsg.Type != tea.KeyEnter {
+			var cmd tea.Cmd
+			w.input, cmd = w.input.Update(msg)
+			return w, cmd
+		}
+
+		value := expandHome(strings.TrimSpace(w.input.Value()))
+		if value == "" {
+			return w, nil
+		}
+
+		if w.step == configStep {
+			w.configFile = value
+			w.input.SetValue(w.themesDir)
+		} else {
+			w.themesDir = value
+		}
+		w.step++
+
+		// There's nothing to download in offline mode
+		if w.step == fetchStep && offline {
+			w.step = doneStep
+			return w, tea.Quit
+		}
+	case fetchStep:
+		switch keyMsg.String() {
+		case "y", "Y":
+			w.fetch = true
+		case "n", "N", tea.KeyEnter.String():
+			w.fetch = false
+		default:
+			return w, nil
+		}
+		w.step = doneStep
+		return w, tea.Quit
+	}
+
+	return w, nil
+}
+
+func (w wizard) View() string {
+	if w.step == doneStep || w.aborted {
+		return ""
+	}
+
+	title := lipgloss.NewStyle().Bold(true).Render("Welcome to Alacritheme")
+	hint := lipgloss.NewStyle().Faint(true)
+
+	var body string
+	switch w.step {
+	case configStep:
+		note := "It will be created if it doesn't exist."
+		if fileExists(expandHome(w.input.Value())) {
+			note = "Found an existing config here."
+		}
+		body = "Where is your Alacritty config?\n\n" + w.input.View() + "\n\n" + hint.Render(note)
+	case themesStep:
+		body = "Where should themes be read from?\n\n" + w.input.View() + "\n\n" + hint.Render("It will be created if it doesn't exist.")
+	case fetchStep:
+		body = "Download the official alacritty-theme collection into\n" + w.themesDir + "? [y/N]"
+	}
+
+	return fmt.Sprintf("\n%s\n\n%s\n\n%s\n", title, body, hint.Render("enter: confirm • esc: cancel"))
+}
+
+// runWizard runs the first-run setup and writes the results into
+// alacritheme's config
+func runWizard() error {
+	final, err := tea.NewProgram(newWizard()).Run()
+	if err != nil {
+		return err
+	}
+
+	w := final.(wizard)
+	if w.aborted {
+		return fmt.Errorf("setup cancelled, set THEMES_DIR and CONFIG_FILE or run alacritheme again")
+	}
+
+	if !fileExists(w.configFile) {
+		if err := os.MkdirAll(filepath.Dir(w.configFile), 0755); err != nil {
+			return err
+		}
+		skeleton := "# Alacritty configuration, see https://alacritty.org/config-alacritty.html\n"
+		if err := os.WriteFile(w.configFile, []byte(skeleton), 0644); err != nil {
+			return err
+		}
+		info("Created %s\n", w.configFile)
+	}
+
+	if err := os.MkdirAll(w.themesDir, 0755); err != nil {
+		return err
+	}
+
+	if w.fetch {
+		info("Downloading the official themes…\n")
+		count, err := fetchOfficialThemes(w.themesDir)
+		if err != nil {
+			return fmt.Errorf("couldn't download themes: %w", err)
+		}
+		info("Added %d themes to %s\n", count, w.themesDir)
+	}
+
+	cfg, _, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+	cfg.ThemesDir, cfg.ConfigFile = w.themesDir, w.configFile
+	if err := saveAppConfig(cfg); err != nil {
+		return err
+	}
+
+	dir, _ := appConfigDir()
+	info("Saved settings to %s\n", filepath.Join(dir, appConfigFile))
+	return nil
+}